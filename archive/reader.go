@@ -0,0 +1,352 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+// maxLineSize is the largest archived record the Reader will accept
+const maxLineSize = 16 * 1024 * 1024
+
+const (
+	// ErrMissingOffset is returned when the archive is missing an offset within the records read
+	ErrMissingOffset = "MissingOffset"
+)
+
+// IsMissingOffset returns true if the error indicates the archive is missing an offset
+func IsMissingOffset(err error) bool {
+	return eventsource.ErrHasCode(err, ErrMissingOffset)
+}
+
+// Option provides functional configuration for a *Reader
+type Option func(*Reader)
+
+// AllowGaps causes the Reader to skip over offsets missing from the archive, e.g. records the
+// archiver was unable to archive, rather than returning an error with code ErrMissingOffset
+func AllowGaps() Option {
+	return func(r *Reader) {
+		r.allowGaps = true
+	}
+}
+
+// file describes a single archive file.  The range of offsets it holds is only known once the
+// file has been loaded
+type file struct {
+	name   string
+	loaded bool
+	first  uint64
+	last   uint64
+}
+
+// Reader implements eventsource.StreamReader on top of an archive of newline delimited
+// StreamRecords such as those written by awscloud.Archiver.  Files may optionally be gzip
+// compressed.  Every archived record must carry its stream offset.
+//
+// Files are expected to appear in offset order when sorted by name, as is the case for the
+// time based keys written by Firehose; adjacent files may overlap.  The Reader locates the file
+// holding the starting offset by binary search, so only the files it reads, plus a handful of
+// others, are ever downloaded.  Records are returned in offset order with duplicates removed.
+//
+// Offsets are expected to be contiguous.  Unless the Reader was created with AllowGaps, Read
+// returns an error with code ErrMissingOffset rather than silently passing over a missing offset
+type Reader struct {
+	source    Source
+	allowGaps bool
+
+	mux    sync.Mutex
+	files  []*file
+	names  map[string]struct{}
+	cached map[string][]eventsource.StreamRecord
+	recent []string
+}
+
+// NewReader returns a Reader for the archive provided by source
+func NewReader(source Source, opts ...Option) *Reader {
+	r := &Reader{
+		source: source,
+		names:  map[string]struct{}{},
+		cached: map[string][]eventsource.StreamRecord{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Read implements eventsource.StreamReader.  Files added to the archive after the Reader was
+// created are picked up once the Reader reaches the end of the files it already knows about
+func (r *Reader) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if startingOffset == 0 {
+		startingOffset = 1
+	}
+	if recordCount <= 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	records, err := r.read(ctx, startingOffset, recordCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) < recordCount || r.gap(startingOffset, records) != 0 {
+		// the end of the known files was reached or an offset may be held by a file added since;
+		// pick up any files added since
+		added, err := r.list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			records, err = r.read(ctx, startingOffset, recordCount)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if missing := r.gap(startingOffset, records); missing != 0 {
+		return nil, eventsource.NewError(nil, ErrMissingOffset, "archive is missing offset %v", missing)
+	}
+
+	if records == nil {
+		records = []eventsource.StreamRecord{}
+	}
+	return records, nil
+}
+
+// gap returns the first offset missing from records, which are expected to begin at
+// startingOffset, or 0 if there is none or gaps are allowed
+func (r *Reader) gap(startingOffset uint64, records []eventsource.StreamRecord) uint64 {
+	if r.allowGaps {
+		return 0
+	}
+
+	expected := startingOffset
+	for _, record := range records {
+		if record.Offset != expected {
+			return expected
+		}
+		expected++
+	}
+
+	return 0
+}
+
+func (r *Reader) read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	// find the first file that may hold startingOffset; files not yet loaded are loaded as the
+	// search probes them.  Empty files are treated as matching so the search never skips past
+	// the offset
+	var searchErr error
+	start := sort.Search(len(r.files), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		f, err := r.span(ctx, i)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return f.last == 0 || f.last >= startingOffset
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	var records []eventsource.StreamRecord
+	for i := start; i < len(r.files); i++ {
+		f, err := r.span(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		// once enough records have been found, a file that starts after the last of them
+		// cannot contribute
+		if len(records) >= recordCount && f.first > records[recordCount-1].Offset {
+			break
+		}
+
+		contents, err := r.load(ctx, f.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range contents {
+			if record.Offset >= startingOffset {
+				records = append(records, record)
+			}
+		}
+		records = dedupe(records)
+	}
+
+	if len(records) > recordCount {
+		records = records[:recordCount]
+	}
+
+	return records, nil
+}
+
+// list adds any files not yet known to the Reader; returns true if files were added
+func (r *Reader) list(ctx context.Context) (bool, error) {
+	names, err := r.source.List(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	added := false
+	for _, name := range names {
+		if _, ok := r.names[name]; ok {
+			continue
+		}
+		r.names[name] = struct{}{}
+		r.files = append(r.files, &file{name: name})
+		added = true
+	}
+
+	if added {
+		sort.Slice(r.files, func(i, j int) bool {
+			return r.files[i].name < r.files[j].name
+		})
+	}
+
+	return added, nil
+}
+
+// span returns the i-th file, loading it if its range of offsets is not yet known
+func (r *Reader) span(ctx context.Context, i int) (*file, error) {
+	f := r.files[i]
+	if !f.loaded {
+		if _, err := r.load(ctx, f.name); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// maxCached is the number of recently loaded files whose records are retained; consecutive reads
+// typically fall within the same file or its neighbour
+const maxCached = 2
+
+// load returns the records held by the named file sorted by offset, and records the file's range
+// of offsets
+func (r *Reader) load(ctx context.Context, name string) ([]eventsource.StreamRecord, error) {
+	if records, ok := r.cached[name]; ok {
+		return records, nil
+	}
+
+	records, err := r.decode(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.Offset == 0 {
+			return nil, errors.Errorf("archive file, %v, holds version %v of aggregate, %v, without an offset", name, record.Version, record.AggregateID)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Offset < records[j].Offset
+	})
+
+	for _, f := range r.files {
+		if f.name == name {
+			f.loaded = true
+			if len(records) > 0 {
+				f.first, f.last = records[0].Offset, records[len(records)-1].Offset
+			}
+		}
+	}
+
+	r.cached[name] = records
+	r.recent = append(r.recent, name)
+	if len(r.recent) > maxCached {
+		delete(r.cached, r.recent[0])
+		r.recent = r.recent[1:]
+	}
+
+	return records, nil
+}
+
+// decode reads every record held by the named file
+func (r *Reader) decode(ctx context.Context, name string) ([]eventsource.StreamRecord, error) {
+	rc, err := r.source.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	reader, err := decompress(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decompress archive file, %v", name)
+	}
+
+	var records []eventsource.StreamRecord
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		record := eventsource.StreamRecord{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode line %v of archive file, %v", line, name)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read archive file, %v", name)
+	}
+
+	return records, nil
+}
+
+// decompress returns a reader over the uncompressed contents of r; gzip content is detected by
+// its magic number
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+
+	return buffered, nil
+}
+
+// dedupe sorts the records by offset and removes duplicates of the same aggregate version
+func dedupe(records []eventsource.StreamRecord) []eventsource.StreamRecord {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Offset < records[j].Offset
+	})
+
+	type key struct {
+		aggregateID string
+		version     int
+	}
+
+	seen := map[key]struct{}{}
+	deduped := records[:0]
+	for _, record := range records {
+		k := key{aggregateID: record.AggregateID, version: record.Version}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		deduped = append(deduped, record)
+	}
+
+	return deduped
+}
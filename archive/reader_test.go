@@ -0,0 +1,213 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/archive"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+)
+
+func record(aggregateID string, version int, offset uint64) eventsource.StreamRecord {
+	return eventsource.StreamRecord{
+		AggregateID: aggregateID,
+		Offset:      offset,
+		Record: eventsource.Record{
+			Version: version,
+			Data:    []byte(aggregateID),
+		},
+	}
+}
+
+func encode(t *testing.T, compress bool, records ...eventsource.StreamRecord) []byte {
+	buf := bytes.NewBuffer(nil)
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		assert.Nil(t, err)
+		buf.Write(data)
+		buf.WriteString("\n")
+	}
+
+	if !compress {
+		return buf.Bytes()
+	}
+
+	compressed := bytes.NewBuffer(nil)
+	w := gzip.NewWriter(compressed)
+	_, err := w.Write(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	return compressed.Bytes()
+}
+
+func offsets(records []eventsource.StreamRecord) []uint64 {
+	values := make([]uint64, 0, len(records))
+	for _, r := range records {
+		values = append(values, r.Offset)
+	}
+	return values
+}
+
+func TestReader_Dir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "2017", "01"), 0755))
+	write := func(name string, data []byte) {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	// files overlap, are out of order and contain a redelivered record
+	write("2017/01/b.gz", encode(t, true, record("abc", 3, 3), record("abc", 4, 5), record("def", 2, 4)))
+	write("2017/01/a", encode(t, false, record("abc", 1, 1), record("def", 1, 2), record("abc", 3, 3)))
+	write("2017/01/c", encode(t, false, record("def", 3, 6)))
+
+	ctx := context.Background()
+	reader := archive.NewReader(archive.DirSource(dir))
+
+	records, err := reader.Read(ctx, 0, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, offsets(records))
+	assert.Equal(t, "abc", records[0].AggregateID)
+	assert.Equal(t, []byte("abc"), records[0].Data)
+
+	records, err = reader.Read(ctx, 3, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{3, 4}, offsets(records))
+
+	records, err = reader.Read(ctx, 7, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 0)
+
+	// files added later are picked up once the end of the archive is reached
+	write("2017/01/d", encode(t, false, record("abc", 5, 7)))
+	records, err = reader.Read(ctx, 7, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{7}, offsets(records))
+}
+
+func TestReader_WithoutOffsets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1"), encode(t, true, record("abc", 1, 0), record("abc", 2, 0)), 0644))
+
+	reader := archive.NewReader(archive.DirSource(dir))
+	_, err = reader.Read(context.Background(), 0, 10)
+	assert.NotNil(t, err)
+}
+
+func TestReader_MissingOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1"), encode(t, false, record("abc", 1, 1), record("abc", 2, 2), record("abc", 4, 4)), 0644))
+
+	ctx := context.Background()
+	reader := archive.NewReader(archive.DirSource(dir))
+	records, err := reader.Read(ctx, 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2}, offsets(records))
+
+	_, err = reader.Read(ctx, 0, 10)
+	assert.True(t, archive.IsMissingOffset(err))
+	assert.Contains(t, err.Error(), "offset 3")
+
+	_, err = reader.Read(ctx, 3, 10)
+	assert.True(t, archive.IsMissingOffset(err))
+
+	// gaps may be permitted
+	reader = archive.NewReader(archive.DirSource(dir), archive.AllowGaps())
+	records, err = reader.Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2, 4}, offsets(records))
+}
+
+// countingSource records the files opened
+type countingSource struct {
+	archive.Source
+	opened []string
+}
+
+func (c *countingSource) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	c.opened = append(c.opened, name)
+	return c.Source.Open(ctx, name)
+}
+
+func TestReader_OpensFewFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 64; i++ {
+		offset := uint64(i*2 + 1)
+		data := encode(t, i%2 == 0, record("abc", int(offset), offset), record("abc", int(offset+1), offset+1))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d", i)), data, 0644))
+	}
+
+	ctx := context.Background()
+	source := &countingSource{Source: archive.DirSource(dir)}
+	reader := archive.NewReader(source)
+
+	records, err := reader.Read(ctx, 101, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{101, 102, 103}, offsets(records))
+	assert.True(t, len(source.opened) <= 10, "expected a binary search; opened %v files", len(source.opened))
+
+	records, err = reader.Read(ctx, 127, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{127, 128}, offsets(records))
+}
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3) ListObjectsV2PagesWithContext(ctx aws.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option) error {
+	out := &s3.ListObjectsV2Output{}
+	for key := range m.objects {
+		if len(key) >= len(*input.Prefix) && key[:len(*input.Prefix)] == *input.Prefix {
+			out.Contents = append(out.Contents, &s3.Object{Key: aws.String(key)})
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (m *mockS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader(m.objects[*input.Key])),
+	}, nil
+}
+
+func TestReader_S3(t *testing.T) {
+	api := &mockS3{
+		objects: map[string][]byte{
+			"events/2017/02": encode(t, true, record("abc", 2, 2)),
+			"events/2017/01": encode(t, false, record("abc", 1, 1)),
+			"other/2017/01":  encode(t, false, record("xyz", 1, 9)),
+		},
+	}
+
+	reader := archive.NewReader(archive.S3Source(api, "bucket", "events/"))
+	records, err := reader.Read(context.Background(), 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2}, offsets(records))
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+)
+
+// Source provides access to the files that make up an archive
+type Source interface {
+	// List returns the names of the files in the archive
+	List(ctx context.Context) ([]string, error)
+
+	// Open returns the contents of the named file
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+type dirSource struct {
+	dir string
+}
+
+func (d dirSource) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(d.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list archive directory, %v", d.dir)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d dirSource) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(d.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open archive file, %v", name)
+	}
+	return f, nil
+}
+
+// DirSource returns a Source that reads every file beneath the specified directory
+func DirSource(dir string) Source {
+	return dirSource{dir: dir}
+}
+
+type s3Source struct {
+	api    s3iface.S3API
+	bucket string
+	prefix string
+}
+
+func (s s3Source) List(ctx context.Context) ([]string, error) {
+	var names []string
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}
+	err := s.api.ListObjectsV2PagesWithContext(ctx, input, func(out *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range out.Contents {
+			names = append(names, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list objects in bucket, %v", s.bucket)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s s3Source) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := s.api.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get object, %v, from bucket, %v", name, s.bucket)
+	}
+	return out.Body, nil
+}
+
+// S3Source returns a Source that reads every object in the bucket beneath the specified prefix.
+// Any S3 compatible service may be used by configuring the endpoint of api
+func S3Source(api s3iface.S3API, bucket, prefix string) Source {
+	return s3Source{
+		api:    api,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
@@ -0,0 +1,213 @@
+package awscloud
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	apex "github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxBatchRecords is the maximum number of records Firehose accepts per PutRecordBatch call
+	maxBatchRecords = 500
+
+	// maxBatchBytes is the maximum size Firehose accepts per PutRecordBatch call
+	maxBatchBytes = 4 * 1024 * 1024
+
+	// maxRecordBytes is the maximum size Firehose accepts per record
+	maxRecordBytes = 1000 * 1024
+)
+
+const (
+	// ErrRecordTooLarge indicates a record exceeds the size Firehose accepts and was not archived
+	ErrRecordTooLarge = "RecordTooLarge"
+)
+
+// IsRecordTooLarge returns true if the error indicates a record was too large to archive
+func IsRecordTooLarge(err error) bool {
+	return eventsource.ErrHasCode(err, ErrRecordTooLarge)
+}
+
+// ArchiverOption provides functional configuration for an *Archiver
+type ArchiverOption func(*Archiver)
+
+// WithMaxAttempts specifies how many times records rejected by Firehose are sent; defaults to 3
+func WithMaxAttempts(n int) ArchiverOption {
+	return func(a *Archiver) {
+		if n > 0 {
+			a.maxAttempts = n
+		}
+	}
+}
+
+// WithRetryDelay specifies how long to wait before resending rejected records; the delay doubles
+// with each attempt
+func WithRetryDelay(d time.Duration) ArchiverOption {
+	return func(a *Archiver) {
+		a.retryDelay = d
+	}
+}
+
+// OnRecordTooLarge specifies a callback to be invoked with each record too large for Firehose to
+// accept, e.g. to log it or save it elsewhere.  The record is skipped, the remaining records are
+// archived and Archive succeeds so the batch is not retried.  The error passed to fn has code
+// ErrRecordTooLarge.
+//
+// Without OnRecordTooLarge, Archive fails with ErrRecordTooLarge before sending any records
+func OnRecordTooLarge(fn func(ctx context.Context, record eventsource.StreamRecord, err error)) ArchiverOption {
+	return func(a *Archiver) {
+		a.onTooLarge = fn
+	}
+}
+
+// Archiver writes StreamRecords to a Firehose delivery stream.  Each record is written as a single
+// line of json so the resulting S3 objects contain newline delimited StreamRecords
+type Archiver struct {
+	api         firehoseiface.FirehoseAPI
+	streamName  string
+	maxAttempts int
+	retryDelay  time.Duration
+	onTooLarge  func(ctx context.Context, record eventsource.StreamRecord, err error)
+}
+
+// Archive sends the records to Firehose, splitting them into as many PutRecordBatch calls as
+// required.  Records rejected by Firehose are resent; an error is returned if any records remain
+// unsent after the final attempt.
+//
+// Sizes are checked before anything is sent.  Records larger than Firehose accepts are passed to
+// the OnRecordTooLarge callback and skipped; if no callback was specified, Archive returns an error
+// with code ErrRecordTooLarge without sending any of the records
+func (a *Archiver) Archive(ctx context.Context, records ...eventsource.StreamRecord) error {
+	encoded := make([][]byte, 0, len(records))
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return errors.Wrapf(err, "unable to encode version %v of aggregate, %v", record.Version, record.AggregateID)
+		}
+		data = append(data, '\n')
+
+		if len(data) > maxRecordBytes {
+			err := eventsource.NewError(nil, ErrRecordTooLarge, "record at offset %v (version %v of aggregate, %v) is %v bytes, exceeding the %v byte limit of delivery stream, %v",
+				record.Offset, record.Version, record.AggregateID, len(data), maxRecordBytes, a.streamName)
+			if a.onTooLarge == nil {
+				return err
+			}
+			a.onTooLarge(ctx, record, err)
+			continue
+		}
+
+		encoded = append(encoded, data)
+	}
+
+	var (
+		batch []*firehose.Record
+		size  int
+	)
+	for _, data := range encoded {
+		if len(batch) == maxBatchRecords || (len(batch) > 0 && size+len(data) > maxBatchBytes) {
+			if err := a.putRecordBatch(ctx, batch); err != nil {
+				return err
+			}
+			batch, size = nil, 0
+		}
+
+		batch = append(batch, &firehose.Record{Data: data})
+		size += len(data)
+	}
+
+	if len(batch) > 0 {
+		if err := a.putRecordBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ArchiveEvent archives the entries contained in a batch of dynamodb stream records read from the
+// stream table of a dynamodbstore.Store created WithStreamTable.  Each archived record carries its
+// stream offset so the archive may be replayed in order; records from the event table itself carry
+// no offset and are rejected
+func (a *Archiver) ArchiveEvent(ctx context.Context, event *apex.Event) error {
+	if event == nil {
+		return nil
+	}
+
+	var records []eventsource.StreamRecord
+	for _, item := range event.Records {
+		changes, err := dynamodbstore.StreamChanges(item)
+		if err != nil {
+			return errors.Wrap(err, "unable to extract changes from stream record")
+		}
+		records = append(records, changes...)
+	}
+
+	return a.Archive(ctx, records...)
+}
+
+func (a *Archiver) putRecordBatch(ctx context.Context, records []*firehose.Record) error {
+	delay := a.retryDelay
+	for attempt := 1; ; attempt++ {
+		out, err := a.api.PutRecordBatchWithContext(ctx, &firehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(a.streamName),
+			Records:            records,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to put records to delivery stream, %v", a.streamName)
+		}
+
+		if aws.Int64Value(out.FailedPutCount) == 0 {
+			return nil
+		}
+
+		// responses are returned in the same order as the request; collect the failures
+		var failed []*firehose.Record
+		var lastErr string
+		for i, response := range out.RequestResponses {
+			if response.ErrorCode != nil && i < len(records) {
+				failed = append(failed, records[i])
+				lastErr = aws.StringValue(response.ErrorCode) + ": " + aws.StringValue(response.ErrorMessage)
+			}
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+
+		if attempt >= a.maxAttempts {
+			return errors.Errorf("unable to put %v record(s) to delivery stream, %v, after %v attempt(s); %v", len(failed), a.streamName, attempt, lastErr)
+		}
+		records = failed
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// NewArchiver returns an Archiver that writes to the specified delivery stream
+func NewArchiver(api firehoseiface.FirehoseAPI, streamName string, opts ...ArchiverOption) *Archiver {
+	a := &Archiver{
+		api:         api,
+		streamName:  streamName,
+		maxAttempts: 3,
+		retryDelay:  100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
@@ -0,0 +1,218 @@
+package awscloud_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/awscloud"
+	apex "github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockFirehose rejects the first record of each of the first failures calls
+type mockFirehose struct {
+	firehoseiface.FirehoseAPI
+	failures  int
+	calls     int
+	delivered [][]byte
+	created   *firehose.CreateDeliveryStreamInput
+}
+
+func (m *mockFirehose) PutRecordBatchWithContext(ctx aws.Context, input *firehose.PutRecordBatchInput, opts ...request.Option) (*firehose.PutRecordBatchOutput, error) {
+	m.calls++
+
+	out := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}
+	for i, record := range input.Records {
+		if i == 0 && m.calls <= m.failures {
+			out.FailedPutCount = aws.Int64(1)
+			out.RequestResponses = append(out.RequestResponses, &firehose.PutRecordBatchResponseEntry{
+				ErrorCode:    aws.String("ServiceUnavailableException"),
+				ErrorMessage: aws.String("slow down"),
+			})
+			continue
+		}
+
+		m.delivered = append(m.delivered, record.Data)
+		out.RequestResponses = append(out.RequestResponses, &firehose.PutRecordBatchResponseEntry{
+			RecordId: aws.String("id"),
+		})
+	}
+
+	return out, nil
+}
+
+func (m *mockFirehose) CreateDeliveryStreamWithContext(ctx aws.Context, input *firehose.CreateDeliveryStreamInput, opts ...request.Option) (*firehose.CreateDeliveryStreamOutput, error) {
+	m.created = input
+	return &firehose.CreateDeliveryStreamOutput{DeliveryStreamARN: aws.String("arn:stream")}, nil
+}
+
+func decode(t *testing.T, delivered [][]byte) []eventsource.StreamRecord {
+	var records []eventsource.StreamRecord
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.Join(delivered, nil)))
+	for scanner.Scan() {
+		record := eventsource.StreamRecord{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestArchiver(t *testing.T) {
+	api := &mockFirehose{failures: 1}
+	archiver := awscloud.NewArchiver(api, "events", awscloud.WithRetryDelay(0))
+
+	records := []eventsource.StreamRecord{
+		{Offset: 1, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a")}},
+		{Offset: 2, AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: []byte("b")}},
+	}
+	err := archiver.Archive(context.Background(), records...)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, api.calls)
+	assert.ElementsMatch(t, records, decode(t, api.delivered))
+}
+
+func TestArchiverGivesUp(t *testing.T) {
+	api := &mockFirehose{failures: 10}
+	archiver := awscloud.NewArchiver(api, "events", awscloud.WithRetryDelay(0), awscloud.WithMaxAttempts(2))
+
+	err := archiver.Archive(context.Background(), eventsource.StreamRecord{AggregateID: "abc"})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, api.calls)
+}
+
+func TestArchiverTooLarge(t *testing.T) {
+	api := &mockFirehose{}
+	archiver := awscloud.NewArchiver(api, "events")
+
+	records := []eventsource.StreamRecord{
+		{Offset: 1, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a")}},
+		{Offset: 2, AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: make([]byte, 1024*1024)}},
+		{Offset: 3, AggregateID: "abc", Record: eventsource.Record{Version: 3, Data: []byte("c")}},
+	}
+	// nothing is sent so a retry of the batch archives nothing twice
+	err := archiver.Archive(context.Background(), records...)
+	assert.True(t, awscloud.IsRecordTooLarge(err))
+	assert.Contains(t, err.Error(), "offset 2")
+	assert.Equal(t, 0, api.calls)
+}
+
+func TestArchiverOnRecordTooLarge(t *testing.T) {
+	var skipped []uint64
+	api := &mockFirehose{}
+	archiver := awscloud.NewArchiver(api, "events",
+		awscloud.OnRecordTooLarge(func(ctx context.Context, record eventsource.StreamRecord, err error) {
+			assert.True(t, awscloud.IsRecordTooLarge(err))
+			skipped = append(skipped, record.Offset)
+		}),
+	)
+
+	records := []eventsource.StreamRecord{
+		{Offset: 1, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a")}},
+		{Offset: 2, AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: make([]byte, 1024*1024)}},
+		{Offset: 3, AggregateID: "abc", Record: eventsource.Record{Version: 3, Data: []byte("c")}},
+	}
+
+	// the remaining records are archived and the batch succeeds
+	err := archiver.Archive(context.Background(), records...)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{2}, skipped)
+	assert.Equal(t, 1, api.calls)
+	assert.Equal(t, []eventsource.StreamRecord{records[0], records[2]}, decode(t, api.delivered))
+}
+
+func TestArchiverEvent(t *testing.T) {
+	api := &mockFirehose{}
+	archiver := awscloud.NewArchiver(api, "events")
+
+	err := archiver.ArchiveEvent(context.Background(), &apex.Event{
+		Records: []*apex.Record{
+			{
+				// counter
+				Dynamodb: &apex.StreamRecord{
+					Keys: map[string]*dynamodb.AttributeValue{
+						"stream": {S: aws.String("events")},
+						"offset": {N: aws.String("0")},
+					},
+					NewImage: map[string]*dynamodb.AttributeValue{
+						"last": {N: aws.String("7")},
+					},
+				},
+			},
+			{
+				Dynamodb: &apex.StreamRecord{
+					Keys: map[string]*dynamodb.AttributeValue{
+						"stream": {S: aws.String("events")},
+						"offset": {N: aws.String("7")},
+					},
+					NewImage: map[string]*dynamodb.AttributeValue{
+						"stream":       {S: aws.String("events")},
+						"offset":       {N: aws.String("7")},
+						"aggregate_id": {S: aws.String("abc")},
+						"version":      {N: aws.String("1")},
+						"data":         {B: []byte("a")},
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []eventsource.StreamRecord{
+		{Offset: 7, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a")}},
+	}, decode(t, api.delivered))
+}
+
+func TestArchiverEventRejectsEventTable(t *testing.T) {
+	api := &mockFirehose{}
+	archiver := awscloud.NewArchiver(api, "events")
+
+	err := archiver.ArchiveEvent(context.Background(), &apex.Event{
+		Records: []*apex.Record{
+			{
+				Dynamodb: &apex.StreamRecord{
+					Keys: map[string]*dynamodb.AttributeValue{
+						"key": {S: aws.String("abc")},
+					},
+					NewImage: map[string]*dynamodb.AttributeValue{
+						"_1": {B: []byte("a")},
+					},
+				},
+			},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, api.calls)
+}
+
+func TestCreateDeliveryStream(t *testing.T) {
+	api := &mockFirehose{}
+
+	_, err := awscloud.CreateDeliveryStream(context.Background(), api, awscloud.DeliveryStreamConfig{Name: "events"})
+	assert.NotNil(t, err)
+
+	arn, err := awscloud.CreateDeliveryStream(context.Background(), api, awscloud.DeliveryStreamConfig{
+		Name:      "events",
+		BucketARN: "arn:bucket",
+		RoleARN:   "arn:role",
+		Prefix:    "events/",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "arn:stream", arn)
+
+	destination := api.created.ExtendedS3DestinationConfiguration
+	assert.Equal(t, "events", *api.created.DeliveryStreamName)
+	assert.Equal(t, "arn:bucket", *destination.BucketARN)
+	assert.Equal(t, "arn:role", *destination.RoleARN)
+	assert.Equal(t, "events/", *destination.Prefix)
+	assert.Equal(t, int64(300), *destination.BufferingHints.IntervalInSeconds)
+	assert.Equal(t, "GZIP", *destination.CompressionFormat)
+	assert.Nil(t, destination.CloudWatchLoggingOptions)
+}
@@ -0,0 +1,109 @@
+package awscloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/pkg/errors"
+)
+
+// DeliveryStreamConfig describes a Firehose delivery stream that writes to S3
+type DeliveryStreamConfig struct {
+	// Name of the delivery stream; required
+	Name string
+
+	// BucketARN of the destination bucket; required
+	BucketARN string
+
+	// RoleARN of the role Firehose assumes to write to the bucket; required
+	RoleARN string
+
+	// Prefix is prepended to the S3 object keys
+	Prefix string
+
+	// ErrorOutputPrefix is prepended to the S3 object keys of records that could not be delivered
+	ErrorOutputPrefix string
+
+	// BufferInterval is how long Firehose buffers records before writing them; defaults to 5 minutes
+	BufferInterval time.Duration
+
+	// BufferSizeMB is how much data Firehose buffers before writing it; defaults to 5
+	BufferSizeMB int64
+
+	// CompressionFormat of the S3 objects; defaults to GZIP
+	CompressionFormat string
+
+	// KMSKeyARN enables server side encryption with the specified key when set
+	KMSKeyARN string
+
+	// LogGroupName and LogStreamName enable CloudWatch logging of delivery errors when set
+	LogGroupName  string
+	LogStreamName string
+}
+
+// CreateDeliveryStream creates a Firehose delivery stream that writes to S3 and returns its ARN
+func CreateDeliveryStream(ctx context.Context, api firehoseiface.FirehoseAPI, cfg DeliveryStreamConfig) (string, error) {
+	if cfg.Name == "" || cfg.BucketARN == "" || cfg.RoleARN == "" {
+		return "", errors.New("delivery stream requires Name, BucketARN, and RoleARN")
+	}
+
+	interval := cfg.BufferInterval
+	if interval == 0 {
+		interval = 5 * time.Minute
+	}
+	size := cfg.BufferSizeMB
+	if size == 0 {
+		size = 5
+	}
+	compression := cfg.CompressionFormat
+	if compression == "" {
+		compression = firehose.CompressionFormatGzip
+	}
+
+	destination := &firehose.ExtendedS3DestinationConfiguration{
+		BucketARN: aws.String(cfg.BucketARN),
+		RoleARN:   aws.String(cfg.RoleARN),
+		BufferingHints: &firehose.BufferingHints{
+			IntervalInSeconds: aws.Int64(int64(interval / time.Second)),
+			SizeInMBs:         aws.Int64(size),
+		},
+		CompressionFormat: aws.String(compression),
+		EncryptionConfiguration: &firehose.EncryptionConfiguration{
+			NoEncryptionConfig: aws.String(firehose.NoEncryptionConfigNoEncryption),
+		},
+	}
+	if cfg.Prefix != "" {
+		destination.Prefix = aws.String(cfg.Prefix)
+	}
+	if cfg.ErrorOutputPrefix != "" {
+		destination.ErrorOutputPrefix = aws.String(cfg.ErrorOutputPrefix)
+	}
+	if cfg.KMSKeyARN != "" {
+		destination.EncryptionConfiguration = &firehose.EncryptionConfiguration{
+			KMSEncryptionConfig: &firehose.KMSEncryptionConfig{
+				AWSKMSKeyARN: aws.String(cfg.KMSKeyARN),
+			},
+		}
+	}
+	if cfg.LogGroupName != "" {
+		destination.CloudWatchLoggingOptions = &firehose.CloudWatchLoggingOptions{
+			Enabled:       aws.Bool(true),
+			LogGroupName:  aws.String(cfg.LogGroupName),
+			LogStreamName: aws.String(cfg.LogStreamName),
+		}
+	}
+
+	out, err := api.CreateDeliveryStreamWithContext(ctx, &firehose.CreateDeliveryStreamInput{
+		DeliveryStreamName:                 aws.String(cfg.Name),
+		DeliveryStreamType:                 aws.String(firehose.DeliveryStreamTypeDirectPut),
+		ExtendedS3DestinationConfiguration: destination,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(out.DeliveryStreamARN), nil
+}
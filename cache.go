@@ -0,0 +1,173 @@
+package eventsource
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CacheStats reports the effectiveness of the aggregate cache
+type CacheStats struct {
+	// Hits contains the number of loads served from the cache
+	Hits uint64
+
+	// Misses contains the number of loads that had to be rebuilt from the store
+	Misses uint64
+
+	// Size contains the number of aggregates currently held by the cache
+	Size int
+}
+
+type cacheEntry struct {
+	aggregateID string
+	aggregate   Aggregate
+	version     int
+	expiresAt   time.Time
+}
+
+// aggregateCache provides an LRU cache of folded aggregates bounded by size and ttl.  Aggregates
+// held by the cache are never handed out directly; callers receive a copy
+type aggregateCache struct {
+	mux     *sync.Mutex
+	size    int
+	ttl     time.Duration
+	lru     *list.List
+	entries map[string]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+func newAggregateCache(size int, ttl time.Duration) *aggregateCache {
+	return &aggregateCache{
+		mux:     &sync.Mutex{},
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns the cached aggregate and its version
+func (c *aggregateCache) get(aggregateID string) (Aggregate, int, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.entries[aggregateID]
+	if !ok {
+		c.misses++
+		return nil, 0, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.lru.Remove(element)
+		delete(c.entries, aggregateID)
+		c.misses++
+		return nil, 0, false
+	}
+
+	c.lru.MoveToFront(element)
+	c.hits++
+	return entry.aggregate, entry.version, true
+}
+
+// put stores the aggregate in the cache; the caller must not modify the aggregate afterwards
+func (c *aggregateCache) put(aggregateID string, aggregate Aggregate, version int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry := &cacheEntry{
+		aggregateID: aggregateID,
+		aggregate:   aggregate,
+		version:     version,
+		expiresAt:   time.Now().Add(c.ttl),
+	}
+
+	if element, ok := c.entries[aggregateID]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[aggregateID] = c.lru.PushFront(entry)
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).aggregateID)
+	}
+}
+
+// remove drops the aggregate from the cache
+func (c *aggregateCache) remove(aggregateID string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if element, ok := c.entries[aggregateID]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, aggregateID)
+	}
+}
+
+func (c *aggregateCache) stats() CacheStats {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return CacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Size:   c.lru.Len(),
+	}
+}
+
+// clone returns a copy of the aggregate.  Aggregates that implement Snapshotter are copied via
+// their snapshot; all others receive a shallow copy
+func (r *Repository) clone(aggregate Aggregate) (Aggregate, error) {
+	if snapshotter, ok := aggregate.(Snapshotter); ok {
+		data, err := snapshotter.MarshalSnapshot()
+		if err != nil {
+			return nil, err
+		}
+
+		dupe := r.New()
+		if err := dupe.(Snapshotter).UnmarshalSnapshot(data); err != nil {
+			return nil, err
+		}
+		return dupe, nil
+	}
+
+	dupe := reflect.New(r.prototype)
+	dupe.Elem().Set(reflect.ValueOf(aggregate).Elem())
+	return dupe.Interface().(Aggregate), nil
+}
+
+// loadCached returns a copy of the cached aggregate along with its version
+func (r *Repository) loadCached(aggregateID string) (Aggregate, int, bool) {
+	if r.cache == nil {
+		return nil, 0, false
+	}
+
+	cached, version, ok := r.cache.get(aggregateID)
+	if !ok {
+		return nil, 0, false
+	}
+
+	aggregate, err := r.clone(cached)
+	if err != nil {
+		r.logf("Unable to copy cached aggregate id, %v: %v", aggregateID, err)
+		r.cache.remove(aggregateID)
+		return nil, 0, false
+	}
+
+	return aggregate, version, true
+}
+
+// CacheStats returns the hit and miss counts for the aggregate cache; returns the zero value if the
+// Repository was not configured WithCache
+func (r *Repository) CacheStats() CacheStats {
+	if r.cache == nil {
+		return CacheStats{}
+	}
+	return r.cache.stats()
+}
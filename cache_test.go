@@ -0,0 +1,143 @@
+package eventsource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCache(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+	store := &loadRecorder{Store: eventsource.New(&Entity{}).Store()}
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(
+			eventsource.NewJSONSerializer(
+				EntityCreated{},
+			),
+		),
+		eventsource.WithCache(10, time.Hour),
+	)
+
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: id}}
+	_, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.CacheStats{Misses: 1, Size: 1}, repo.CacheStats())
+
+	// When
+	version, err := repo.Apply(ctx, cmd)
+
+	// Then - the aggregate is served from the cache and only newer events are requested
+	assert.Nil(t, err)
+	assert.Equal(t, 2, version)
+	assert.Equal(t, 2, store.fromVersions[len(store.fromVersions)-1])
+	assert.Equal(t, eventsource.CacheStats{Hits: 1, Misses: 1, Size: 1}, repo.CacheStats())
+
+	// And - modifying a loaded aggregate does not modify the cache
+	v, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v.(*Entity).Version)
+	v.(*Entity).Version = 100
+
+	v, err = repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v.(*Entity).Version)
+}
+
+func TestWithCacheCatchUp(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+	serializer := eventsource.NewJSONSerializer(EntityCreated{}, EntityNameSet{})
+	repo := eventsource.New(&Entity{},
+		eventsource.WithSerializer(serializer),
+		eventsource.WithCache(10, 0),
+	)
+
+	_, err := repo.Apply(ctx, &CreateEntity{CommandModel: eventsource.CommandModel{ID: id}})
+	assert.Nil(t, err)
+
+	// When - another writer appends events directly to the store
+	err = repo.Save(ctx, &EntityNameSet{
+		Model: eventsource.Model{ID: id, Version: 2},
+		Name:  "Jones",
+	})
+	assert.Nil(t, err)
+
+	// Then - the cached aggregate is brought up to date
+	v, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v.(*Entity).Version)
+	assert.Equal(t, "Jones", v.(*Entity).Name)
+}
+
+func TestWithCacheLimits(t *testing.T) {
+	ctx := context.Background()
+	newRepository := func(size int, ttl time.Duration) *eventsource.Repository {
+		return eventsource.New(&Entity{},
+			eventsource.WithSerializer(
+				eventsource.NewJSONSerializer(
+					EntityCreated{},
+				),
+			),
+			eventsource.WithCache(size, ttl),
+		)
+	}
+
+	t.Run("size", func(t *testing.T) {
+		repo := newRepository(1, 0)
+		for _, id := range []string{"a", "b", "a"} {
+			_, err := repo.Apply(ctx, &CreateEntity{CommandModel: eventsource.CommandModel{ID: id}})
+			assert.Nil(t, err)
+		}
+
+		// "a" was evicted by "b"
+		assert.Equal(t, eventsource.CacheStats{Misses: 3, Size: 1}, repo.CacheStats())
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		repo := newRepository(10, time.Millisecond)
+		cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "a"}}
+
+		_, err := repo.Apply(ctx, cmd)
+		assert.Nil(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = repo.Apply(ctx, cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), repo.CacheStats().Hits)
+		assert.Equal(t, uint64(2), repo.CacheStats().Misses)
+	})
+}
+
+func TestWithCacheConflict(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{
+		VersionedStore: eventsource.New(&Entity{}).Store().(eventsource.VersionedStore),
+	}
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(
+			eventsource.NewJSONSerializer(
+				EntityCreated{},
+			),
+		),
+		eventsource.WithCache(10, 0),
+	)
+
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "123"}}
+	_, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+
+	// When
+	store.races = 1
+	_, err = repo.Apply(ctx, cmd)
+
+	// Then - the conflicting aggregate is dropped from the cache
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+	assert.Equal(t, 0, repo.CacheStats().Size)
+}
@@ -0,0 +1,6 @@
+package deadletter_test
+
+import "testing"
+
+func TestCompiles(t *testing.T) {
+}
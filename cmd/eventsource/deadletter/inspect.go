@@ -0,0 +1,41 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Inspect holds the deadletter inspect command
+var Inspect = cli.Command{
+	Name:  "inspect",
+	Usage: "prints a single dead letter, including the record, as json",
+	Flags: []cli.Flag{
+		flagName,
+		flagSubscription,
+		cli.Uint64Flag{
+			Name:        "offset",
+			Usage:       "offset of the dead lettered record",
+			Destination: &opts.Offset,
+		},
+		flagRegion,
+		flagEndpoint,
+	},
+	Action: inspectAction,
+}
+
+func inspectAction(_ *cli.Context) error {
+	store := newStore()
+
+	letter, err := store.GetDeadLetter(context.Background(), opts.Subscription, opts.Offset)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(letter)
+}
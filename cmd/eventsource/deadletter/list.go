@@ -0,0 +1,50 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// List holds the deadletter list command
+var List = cli.Command{
+	Name:  "list",
+	Usage: "lists the dead letters of a subscription",
+	Flags: []cli.Flag{
+		flagName,
+		flagSubscription,
+		flagRegion,
+		flagEndpoint,
+	},
+	Action: listAction,
+}
+
+func listAction(_ *cli.Context) error {
+	store := newStore()
+
+	letters, err := store.ListDeadLetters(context.Background(), opts.Subscription)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "OFFSET\tAGGREGATE ID\tVERSION\tATTEMPTS\tFAILED AT\tREDRIVE\tERROR")
+	for _, letter := range letters {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			letter.Record.Offset,
+			letter.Record.AggregateID,
+			letter.Record.Version,
+			letter.Attempts,
+			letter.FailedAt.Format(time.RFC3339),
+			letter.Redrive,
+			letter.Error,
+		)
+	}
+
+	return w.Flush()
+}
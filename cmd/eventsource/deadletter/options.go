@@ -0,0 +1,78 @@
+package deadletter
+
+import (
+	"log"
+
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	awsResourceInUse = "ResourceInUseException"
+)
+
+type options struct {
+	Subscription string
+	Offset       uint64
+	AWS          struct {
+		Region string
+	}
+	DynamoDB struct {
+		Endpoint      string
+		TableName     string
+		ReadCapacity  int64
+		WriteCapacity int64
+	}
+}
+
+var opts = options{}
+
+var (
+	flagName = cli.StringFlag{
+		Name:        "name",
+		Usage:       "name of the dead letter table",
+		Destination: &opts.DynamoDB.TableName,
+	}
+	flagSubscription = cli.StringFlag{
+		Name:        "subscription",
+		Usage:       "name of the subscription",
+		Destination: &opts.Subscription,
+	}
+	flagRegion = cli.StringFlag{
+		Name:        "region",
+		Usage:       "AWS region the table is located in",
+		Value:       dynamodbstore.DefaultRegion,
+		EnvVar:      "AWS_DEFAULT_REGION",
+		Destination: &opts.AWS.Region,
+	}
+	flagEndpoint = cli.StringFlag{
+		Name:        "endpoint",
+		Usage:       "specify the DynamoDB endpoint; useful for local testing",
+		EnvVar:      "DYNAMODB_ENDPOINT",
+		Destination: &opts.DynamoDB.Endpoint,
+	}
+)
+
+func newStore() *dynamodbstore.DeadLetterStore {
+	if opts.DynamoDB.TableName == "" {
+		log.Fatalln("--name is required")
+	}
+	if opts.Subscription == "" {
+		log.Fatalln("--subscription is required")
+	}
+
+	api, err := awscloud.DynamoDB(opts.AWS.Region, opts.DynamoDB.Endpoint)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store, err := dynamodbstore.NewDeadLetterStore(opts.DynamoDB.TableName,
+		dynamodbstore.WithDynamoDB(api),
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
@@ -0,0 +1,50 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/altairsix/eventsource/subscription"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Redrive holds the deadletter redrive command
+var Redrive = cli.Command{
+	Name:  "redrive",
+	Usage: "marks dead letters to be retried by their subscription the next time it is idle",
+	Description: "redrive does not deliver records itself; it only marks dead letters.  Marked records are\n" +
+		"   redelivered by a running subscription configured with this dead letter store, and only once\n" +
+		"   that subscription has caught up and is idle.  Until then, marked letters remain in the table.",
+	Flags: []cli.Flag{
+		flagName,
+		flagSubscription,
+		cli.Int64SliceFlag{
+			Name:  "offset",
+			Usage: "offset of a dead lettered record to redrive; may be repeated.  redrives all dead letters if omitted",
+		},
+		flagRegion,
+		flagEndpoint,
+	},
+	Action: redriveAction,
+}
+
+func redriveAction(c *cli.Context) error {
+	store := newStore()
+
+	var offsets []uint64
+	for _, offset := range c.Int64Slice("offset") {
+		offsets = append(offsets, uint64(offset))
+	}
+
+	n, err := subscription.MarkRedrive(context.Background(), store, opts.Subscription, offsets...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Marked %v dead letter(s) of subscription, %v, for redrive.\n", n, opts.Subscription)
+	fmt.Fprintln(os.Stdout, "They will be redelivered by the running subscription the next time it is idle.")
+
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 
+	"github.com/altairsix/eventsource/cmd/eventsource/deadletter"
 	"github.com/altairsix/eventsource/cmd/eventsource/dynamodb"
 	"github.com/altairsix/eventsource/cmd/eventsource/singleton"
 	"gopkg.in/urfave/cli.v1"
@@ -29,6 +30,16 @@ func main() {
 				singleton.DeleteTable,
 			},
 		},
+		{
+			Name:  "deadletter",
+			Usage: "inspects and redrives records subscriptions were unable to handle",
+			Subcommands: []cli.Command{
+				deadletter.CreateTable,
+				deadletter.List,
+				deadletter.Inspect,
+				deadletter.Redrive,
+			},
+		},
 	}
 	app.Run(os.Args)
 }
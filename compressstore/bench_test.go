@@ -0,0 +1,157 @@
+package compressstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/compressstore"
+)
+
+// events holds json payloads representative of verbose domain events
+var events = func() [][]byte {
+	type LineItem struct {
+		SKU         string  `json:"sku"`
+		Description string  `json:"description"`
+		Quantity    int     `json:"quantity"`
+		UnitPrice   float64 `json:"unitPrice"`
+		Currency    string  `json:"currency"`
+	}
+	type Address struct {
+		Line1      string `json:"line1"`
+		Line2      string `json:"line2,omitempty"`
+		City       string `json:"city"`
+		Region     string `json:"region"`
+		PostalCode string `json:"postalCode"`
+		Country    string `json:"country"`
+	}
+	type OrderPlaced struct {
+		ID        string     `json:"id"`
+		Version   int        `json:"version"`
+		At        time.Time  `json:"at"`
+		Customer  string     `json:"customerId"`
+		Email     string     `json:"email"`
+		Shipping  Address    `json:"shippingAddress"`
+		Billing   Address    `json:"billingAddress"`
+		LineItems []LineItem `json:"lineItems"`
+		Notes     string     `json:"notes"`
+	}
+
+	at := time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)
+	address := Address{
+		Line1:      "1600 Amphitheatre Parkway",
+		City:       "Mountain View",
+		Region:     "CA",
+		PostalCode: "94043",
+		Country:    "US",
+	}
+
+	var payloads [][]byte
+	for _, n := range []int{5, 40} {
+		event := OrderPlaced{
+			ID:       "order-8c1f0b6e-3a9d-4f1c-9a57-2d1f6b0e4c11",
+			Version:  1,
+			At:       at,
+			Customer: "customer-4b7e9a1d-6c2f-4e8a-b3d5-9f0a1c2e3d4b",
+			Email:    "someone@example.com",
+			Shipping: address,
+			Billing:  address,
+			Notes:    "Please leave the package with the front desk if no one is available to sign for it.",
+		}
+		for i := 0; i < n; i++ {
+			event.LineItems = append(event.LineItems, LineItem{
+				SKU:         fmt.Sprintf("SKU-%06d", 1000+i*37),
+				Description: fmt.Sprintf("Widget, model %v, standard finish", i),
+				Quantity:    1 + i%4,
+				UnitPrice:   19.99 + float64(i),
+				Currency:    "USD",
+			})
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			panic(err)
+		}
+		payloads = append(payloads, data)
+	}
+
+	return payloads
+}()
+
+// captureStore retains the most recently saved records
+type captureStore struct {
+	history eventsource.History
+}
+
+func (c *captureStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	c.history = records
+	return nil
+}
+
+func (c *captureStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	history := make(eventsource.History, len(c.history))
+	copy(history, c.history)
+	return history, nil
+}
+
+func benchmarkSave(b *testing.B, codec compressstore.Codec, data []byte) {
+	ctx := context.Background()
+	inner := &captureStore{}
+	store := compressstore.New(inner, compressstore.WithCodec(codec))
+	record := eventsource.Record{Version: 1, Data: data}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := store.Save(ctx, "abc", record); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	b.Logf("%v: %v bytes compressed to %v bytes", codec, len(data), len(inner.history[0].Data))
+}
+
+func benchmarkLoad(b *testing.B, codec compressstore.Codec, data []byte) {
+	ctx := context.Background()
+	inner := &captureStore{}
+	store := compressstore.New(inner, compressstore.WithCodec(codec))
+	if err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: data}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := store.Load(ctx, "abc", 0, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	for _, codec := range codecs {
+		for _, data := range events {
+			b.Run(fmt.Sprintf("%v/%v", codec, len(data)), func(b *testing.B) {
+				benchmarkSave(b, codec, data)
+			})
+		}
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for _, codec := range codecs {
+		for _, data := range events {
+			b.Run(fmt.Sprintf("%v/%v", codec, len(data)), func(b *testing.B) {
+				benchmarkLoad(b, codec, data)
+			})
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package compressstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Codec identifies the compression algorithm applied to a record.  The value of each Codec is
+// persisted alongside the record and must never change
+type Codec byte
+
+const (
+	// none marks records stored uncompressed behind a header; used only when the raw data would
+	// otherwise be mistaken for a header
+	none Codec = 0
+
+	// Gzip compresses using compress/gzip at the default level
+	Gzip Codec = 1
+
+	// Zstd compresses using Zstandard; generally the best ratio for the cpu spent
+	Zstd Codec = 2
+
+	// Snappy compresses using snappy; the fastest, with the lowest ratio
+	Snappy Codec = 3
+)
+
+// String implements fmt.Stringer
+func (c Codec) String() string {
+	switch c {
+	case none:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Snappy:
+		return "snappy"
+	default:
+		return "unknown"
+	}
+}
+
+// magic precedes the codec byte of each header.  Uncompressed json, the common case for data saved
+// before compression was enabled, never begins with a zero byte
+var magic = []byte{0x00, 'E', 'S', 'Z'}
+
+const headerSize = 5
+
+var (
+	gzipWriters = sync.Pool{
+		New: func() interface{} {
+			return gzip.NewWriter(nil)
+		},
+	}
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+// hasHeader returns true if data begins with a compression header
+func hasHeader(data []byte) bool {
+	return len(data) >= headerSize && bytes.Equal(data[:len(magic)], magic)
+}
+
+// encode returns data compressed with codec, preceded by a header
+func encode(codec Codec, data []byte) ([]byte, error) {
+	buf := make([]byte, 0, headerSize+len(data)/2)
+	buf = append(buf, magic...)
+	buf = append(buf, byte(codec))
+
+	switch codec {
+	case none:
+		return append(buf, data...), nil
+
+	case Gzip:
+		b := bytes.NewBuffer(buf)
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+
+		w.Reset(b)
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Wrap(err, "unable to gzip record")
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrap(err, "unable to gzip record")
+		}
+		return b.Bytes(), nil
+
+	case Zstd:
+		if err := initZstd(); err != nil {
+			return nil, errors.Wrap(err, "unable to create zstd encoder")
+		}
+		return zstdEncoder.EncodeAll(data, buf), nil
+
+	case Snappy:
+		encoded := snappy.Encode(nil, data)
+		return append(buf, encoded...), nil
+
+	default:
+		return nil, errors.Errorf("unknown codec, %v", byte(codec))
+	}
+}
+
+// decode returns the data following the header, decompressed
+func decode(data []byte) ([]byte, error) {
+	codec, payload := Codec(data[len(magic)]), data[headerSize:]
+
+	switch codec {
+	case none:
+		return append([]byte(nil), payload...), nil
+
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to gunzip record")
+		}
+		defer r.Close()
+
+		decoded, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to gunzip record")
+		}
+		return decoded, nil
+
+	case Zstd:
+		if err := initZstd(); err != nil {
+			return nil, errors.Wrap(err, "unable to create zstd decoder")
+		}
+		decoded, err := zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decompress zstd record")
+		}
+		return decoded, nil
+
+	case Snappy:
+		decoded, err := snappy.Decode(nil, payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decompress snappy record")
+		}
+		return decoded, nil
+
+	default:
+		return nil, errors.Errorf("unknown codec, %v", byte(codec))
+	}
+}
@@ -0,0 +1,185 @@
+package compressstore
+
+import (
+	"context"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMinSize is the size below which records are saved uncompressed by default
+	DefaultMinSize = 128
+)
+
+// Option provides functional configuration for a *Store
+type Option func(*Store)
+
+// WithCodec specifies the algorithm used to compress newly saved records; defaults to Gzip.
+// Records compressed with any codec can always be loaded
+func WithCodec(codec Codec) Option {
+	return func(s *Store) {
+		s.codec = codec
+	}
+}
+
+// WithMinSize specifies the size in bytes below which records are saved uncompressed
+func WithMinSize(n int) Option {
+	return func(s *Store) {
+		s.minSize = n
+	}
+}
+
+// Store wraps an eventsource.Store and compresses the Data of each record before saving it.
+// Compressed records are preceded by a short header identifying the codec used; records without
+// the header, such as those saved before compression was enabled, are loaded as is.  Records that
+// are small, or that compression would not shrink, are saved uncompressed
+type Store struct {
+	store   eventsource.Store
+	codec   Codec
+	minSize int
+}
+
+// New returns a Store that compresses records before saving them to store
+func New(store eventsource.Store, opts ...Option) *Store {
+	s := &Store{
+		store:   store,
+		codec:   Gzip,
+		minSize: DefaultMinSize,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Save compresses and saves the records
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	compressed, err := s.compress(records)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Save(ctx, aggregateID, compressed...)
+}
+
+// SaveVersion compresses and saves the records provided the aggregate is at expectedVersion;
+// implements eventsource.VersionedStore.  If the underlying store is not a VersionedStore,
+// SaveVersion falls back to Save
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	store, ok := s.store.(eventsource.VersionedStore)
+	if !ok {
+		return s.Save(ctx, aggregateID, records...)
+	}
+
+	compressed, err := s.compress(records)
+	if err != nil {
+		return err
+	}
+
+	return store.SaveVersion(ctx, aggregateID, expectedVersion, compressed...)
+}
+
+// SaveAll compresses and saves several batches atomically; implements eventsource.BatchStore.  An
+// error with code ErrUnsupported is returned if the underlying store is not a BatchStore
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	store, ok := s.store.(eventsource.BatchStore)
+	if !ok {
+		return eventsource.NewError(nil, eventsource.ErrUnsupported, "store, %T, is unable to save multiple aggregates atomically", s.store)
+	}
+
+	compressed := make([]eventsource.Batch, 0, len(batches))
+	for _, batch := range batches {
+		records, err := s.compress(batch.Records)
+		if err != nil {
+			return err
+		}
+		compressed = append(compressed, eventsource.Batch{AggregateID: batch.AggregateID, Records: records})
+	}
+
+	return store.SaveAll(ctx, compressed...)
+}
+
+// Load and decompress the history of the aggregate
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	history, err := s.store.Load(ctx, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, record := range history {
+		data, err := decompress(record.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to load version %v of aggregate, %v", record.Version, aggregateID)
+		}
+		history[i].Data = data
+	}
+
+	return history, nil
+}
+
+// Read implements eventsource.StreamReader.  An error with code ErrUnsupported is returned if the
+// underlying store is not a StreamReader
+func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	reader, ok := s.store.(eventsource.StreamReader)
+	if !ok {
+		return nil, eventsource.NewError(nil, eventsource.ErrUnsupported, "store, %T, is unable to read the event stream", s.store)
+	}
+
+	records, err := reader.Read(ctx, startingOffset, recordCount)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, record := range records {
+		data, err := decompress(record.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read offset %v", record.Offset)
+		}
+		records[i].Data = data
+	}
+
+	return records, nil
+}
+
+func (s *Store) compress(records []eventsource.Record) ([]eventsource.Record, error) {
+	compressed := make([]eventsource.Record, 0, len(records))
+	for _, record := range records {
+		data, err := s.encode(record.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to compress version %v", record.Version)
+		}
+		record.Data = data
+		compressed = append(compressed, record)
+	}
+
+	return compressed, nil
+}
+
+func (s *Store) encode(data []byte) ([]byte, error) {
+	if len(data) >= s.minSize {
+		encoded, err := encode(s.codec, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) < len(data) {
+			return encoded, nil
+		}
+	}
+
+	if hasHeader(data) {
+		// prevent the raw data from being mistaken for a header when loaded
+		return encode(none, data)
+	}
+
+	return data, nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	if !hasHeader(data) {
+		return data, nil
+	}
+	return decode(data)
+}
@@ -0,0 +1,193 @@
+package compressstore_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/compressstore"
+	"github.com/stretchr/testify/assert"
+)
+
+var codecs = []compressstore.Codec{
+	compressstore.Gzip,
+	compressstore.Zstd,
+	compressstore.Snappy,
+}
+
+// streamStore keeps saved records in the order they were saved.  As with the real stores,
+// resaving a version is only accepted if the record is unchanged
+type streamStore struct {
+	stream []eventsource.StreamRecord
+}
+
+func (s *streamStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	for _, record := range records {
+		if existing, ok := s.find(aggregateID, record.Version); ok {
+			if !reflect.DeepEqual(existing.Record, record) {
+				return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "version %v of %v already exists", record.Version, aggregateID)
+			}
+			continue
+		}
+		s.stream = append(s.stream, eventsource.StreamRecord{
+			Record:      record,
+			Offset:      uint64(len(s.stream) + 1),
+			AggregateID: aggregateID,
+		})
+	}
+	return nil
+}
+
+func (s *streamStore) find(aggregateID string, version int) (eventsource.StreamRecord, bool) {
+	for _, record := range s.stream {
+		if record.AggregateID == aggregateID && record.Version == version {
+			return record, true
+		}
+	}
+	return eventsource.StreamRecord{}, false
+}
+
+func (s *streamStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	history := eventsource.History{}
+	for _, record := range s.stream {
+		if record.AggregateID == aggregateID && record.Version >= fromVersion && (toVersion == 0 || record.Version <= toVersion) {
+			history = append(history, record.Record)
+		}
+	}
+	sort.Sort(history)
+	return history, nil
+}
+
+func (s *streamStore) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	records := []eventsource.StreamRecord{}
+	for _, record := range s.stream {
+		if record.Offset >= startingOffset && len(records) < recordCount {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func TestStore_Implements(t *testing.T) {
+	var store interface{} = compressstore.New(nil)
+
+	_, ok := store.(eventsource.VersionedStore)
+	assert.True(t, ok)
+
+	_, ok = store.(eventsource.BatchStore)
+	assert.True(t, ok)
+
+	_, ok = store.(eventsource.StreamReader)
+	assert.True(t, ok)
+}
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	for _, codec := range codecs {
+		t.Run(codec.String(), func(t *testing.T) {
+			ctx := context.Background()
+			inner := &streamStore{}
+			store := compressstore.New(inner, compressstore.WithCodec(codec))
+
+			history := eventsource.History{
+				{Version: 1, Data: events[0]},
+				{Version: 2, Data: events[1]},
+				{Version: 3, Data: []byte(`{"small":true}`)},
+			}
+			err := store.Save(ctx, "abc", history...)
+			assert.Nil(t, err)
+
+			raw, err := inner.Load(ctx, "abc", 0, 0)
+			assert.Nil(t, err)
+			assert.True(t, len(raw[0].Data) < len(events[0]), "large records should be compressed")
+			assert.Equal(t, history[2].Data, raw[2].Data, "small records should be saved as is")
+
+			found, err := store.Load(ctx, "abc", 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, history, found)
+
+			err = store.Save(ctx, "abc", history...)
+			assert.Nil(t, err, "saving the same records again should remain idempotent")
+
+			records, err := store.Read(ctx, 0, 10)
+			assert.Nil(t, err)
+			assert.Len(t, records, 3)
+			for i, record := range records {
+				assert.Equal(t, history[i], record.Record)
+			}
+		})
+	}
+}
+
+func TestStore_MixedCodecs(t *testing.T) {
+	ctx := context.Background()
+	inner := &streamStore{}
+
+	// records saved before compression was enabled
+	err := inner.Save(ctx, "abc", eventsource.Record{Version: 1, Data: events[0]})
+	assert.Nil(t, err)
+
+	for i, codec := range codecs {
+		store := compressstore.New(inner, compressstore.WithCodec(codec))
+		err := store.Save(ctx, "abc", eventsource.Record{Version: i + 2, Data: events[i%len(events)]})
+		assert.Nil(t, err)
+	}
+
+	found, err := compressstore.New(inner).Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, found, 4)
+	assert.Equal(t, events[0], found[0].Data)
+	for i := range codecs {
+		assert.Equal(t, events[i%len(events)], found[i+1].Data)
+	}
+}
+
+func TestStore_DataResemblingHeader(t *testing.T) {
+	ctx := context.Background()
+	inner := &streamStore{}
+	store := compressstore.New(inner)
+
+	data := []byte{0x00, 'E', 'S', 'Z', 0x01, 'a', 'b', 'c'}
+	err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: data})
+	assert.Nil(t, err)
+
+	raw, err := inner.Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Equal(data, raw[0].Data))
+
+	found, err := store.Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, data, found[0].Data)
+}
+
+func TestStore_Incompressible(t *testing.T) {
+	ctx := context.Background()
+	inner := &streamStore{}
+	store := compressstore.New(inner, compressstore.WithMinSize(0))
+
+	data := make([]byte, 256)
+	rand.New(rand.NewSource(1)).Read(data)
+	err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: data})
+	assert.Nil(t, err)
+
+	raw, err := inner.Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, data, raw[0].Data, "records that compression does not shrink should be saved as is")
+}
+
+func TestStore_Unsupported(t *testing.T) {
+	ctx := context.Background()
+	store := compressstore.New(&captureStore{})
+
+	err := store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: events[0]})
+	assert.Nil(t, err, "SaveVersion should fall back to Save")
+
+	err = store.SaveAll(ctx, eventsource.Batch{AggregateID: "abc"})
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+
+	_, err = store.Read(ctx, 0, 10)
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+}
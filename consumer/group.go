@@ -0,0 +1,472 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPartitions is the number of partitions the stream is split into by default.  Each
+	// partition reads the entire stream; see WithPartitions
+	DefaultPartitions = 16
+
+	// DefaultLeaseDuration is how long partition and member leases last by default.  Leases are
+	// renewed three times per lease duration
+	DefaultLeaseDuration = 30 * time.Second
+)
+
+// Partition returns the partition, in the range [0, partitions), that records for the aggregate
+// are assigned to
+func Partition(aggregateID string, partitions int) int {
+	h := fnv.New32a()
+	io.WriteString(h, aggregateID)
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// Option provides functional configuration for a *Group
+type Option func(*Group)
+
+// WithPartitions specifies the number of partitions the stream is split into.  Every member of the
+// group must use the same value and it must not change once the group has saved checkpoints.
+//
+// Each partition runs its own subscription that reads the entire stream and skips the records of
+// other partitions, so a member owning n partitions reads the stream n times and the group as a
+// whole reads it once per partition.  Choose the smallest n that provides the parallelism needed
+func WithPartitions(n int) Option {
+	return func(g *Group) {
+		if n > 0 {
+			g.partitions = n
+		}
+	}
+}
+
+// WithLeaseStore specifies how members coordinate partition ownership; by default leases are held
+// in memory so only members within the same process can cooperate
+func WithLeaseStore(leases LeaseStore) Option {
+	return func(g *Group) {
+		g.leases = leases
+	}
+}
+
+// WithCheckpointStore specifies where the progress of each partition is saved.  The store must be
+// shared by all members so a partition resumes where its previous owner left off
+func WithCheckpointStore(checkpoints subscription.CheckpointStore) Option {
+	return func(g *Group) {
+		g.checkpoints = checkpoints
+	}
+}
+
+// WithLeaseDuration specifies how long a lease lasts before another member may take it over.  This
+// bounds how long a partition goes unprocessed after its owner dies.  Short leases are taken over
+// sooner but make it more likely a stalled member overlaps with the next owner; see Group
+func WithLeaseDuration(d time.Duration) Option {
+	return func(g *Group) {
+		if d > 0 {
+			g.leaseDuration = d
+		}
+	}
+}
+
+// WithOwner specifies the identity of this member; must be unique within the group.  Defaults to a
+// value derived from the hostname and process id
+func WithOwner(owner string) Option {
+	return func(g *Group) {
+		g.owner = owner
+	}
+}
+
+// WithSubscriptionOptions specifies options passed to the subscription of each partition
+func WithSubscriptionOptions(opts ...subscription.Option) Option {
+	return func(g *Group) {
+		g.subscriptionOpts = append(g.subscriptionOpts, opts...)
+	}
+}
+
+// WithDebug will generate additional logging useful for debugging
+func WithDebug(w io.Writer) Option {
+	return func(g *Group) {
+		g.writer = w
+		g.debug = true
+	}
+}
+
+// worker is the subscription processing a single partition
+type worker struct {
+	sub  *subscription.Subscription
+	done chan struct{}
+}
+
+// Group is a single member of a consumer group.  Members split the stream into partitions by a hash
+// of StreamRecord.AggregateID so records for a given aggregate are always handled in order by one
+// member.  Each member holds leases on a fair share of the partitions and runs a subscription for
+// each; when a member stops or dies its partitions are taken over by the remaining members.
+//
+// Every partition reads the entire stream and skips the records belonging to other partitions,
+// so the number of partitions should be kept modest.
+//
+// Leases are not fencing tokens; checkpoints are saved without checking the lease is still held.
+// A member that stalls for longer than the lease duration, e.g. during a long GC pause, may keep
+// handling records of a partition and saving its checkpoint after another member has taken the
+// partition over.  Records may then be handled twice and the checkpoint may briefly move backwards,
+// but no record is skipped.  Handlers must therefore be idempotent
+type Group struct {
+	name             string
+	reader           eventsource.StreamReader
+	handler          subscription.Handler
+	partitions       int
+	leases           LeaseStore
+	checkpoints      subscription.CheckpointStore
+	leaseDuration    time.Duration
+	owner            string
+	subscriptionOpts []subscription.Option
+	writer           io.Writer
+	debug            bool
+
+	mux     sync.Mutex
+	slot    int
+	workers map[int]*worker
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func (g *Group) logf(format string, args ...interface{}) {
+	if !g.debug {
+		return
+	}
+
+	now := time.Now().Format(time.StampMilli)
+	io.WriteString(g.writer, now)
+	io.WriteString(g.writer, " ")
+
+	fmt.Fprintf(g.writer, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		io.WriteString(g.writer, "\n")
+	}
+}
+
+func (g *Group) memberKey(slot int) string {
+	return g.name + "/member/" + strconv.Itoa(slot)
+}
+
+func (g *Group) partitionKey(partition int) string {
+	return g.name + "/partition/" + strconv.Itoa(partition)
+}
+
+// Partitions returns the partitions currently owned by this member
+func (g *Group) Partitions() []int {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	partitions := make([]int, 0, len(g.workers))
+	for partition := range g.workers {
+		partitions = append(partitions, partition)
+	}
+	sort.Ints(partitions)
+	return partitions
+}
+
+// Run joins the group and processes the partitions assigned to this member until the context is
+// cancelled, Stop is called, or a handler returns an error.  On return, all partitions are
+// released so they can be taken over by the remaining members.  Run returns nil when stopped via
+// Stop.  Stop is permanent; only a Run that returned because its context was cancelled or a handler
+// failed may be called again.  An error is returned if the member is already running
+func (g *Group) Run(ctx context.Context) error {
+	g.mux.Lock()
+	if g.running {
+		g.mux.Unlock()
+		return errors.Errorf("member, %v, of group, %v, is already running", g.owner, g.name)
+	}
+	g.running = true
+	done := make(chan struct{})
+	g.done = done
+	g.mux.Unlock()
+
+	defer func() {
+		g.mux.Lock()
+		g.running = false
+		g.mux.Unlock()
+		close(done)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	failed := make(chan error, 1)
+	defer g.leave()
+
+	interval := g.leaseDuration / 3
+	for {
+		if err := g.rebalance(ctx, failed); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-g.stop:
+			timer.Stop()
+			return nil
+		case err := <-failed:
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// rebalance renews the leases held by this member, then sheds or takes partitions until the member
+// holds its fair share
+func (g *Group) rebalance(ctx context.Context, failed chan error) error {
+	keys := make([]string, 0, 2*g.partitions)
+	for i := 0; i < g.partitions; i++ {
+		keys = append(keys, g.memberKey(i), g.partitionKey(i))
+	}
+
+	leases, err := g.leases.Leases(ctx, keys...)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read leases for group, %v", g.name)
+	}
+	held := map[string]string{}
+	for _, lease := range leases {
+		held[lease.Key] = lease.Owner
+	}
+
+	if err := g.join(ctx, held); err != nil {
+		return err
+	}
+	if g.slot < 0 {
+		// more members than partitions; wait for a slot to free up
+		g.shed(ctx, 0)
+		return nil
+	}
+
+	// members are ordered by slot; lower slots take any remainder
+	var members []int
+	for i := 0; i < g.partitions; i++ {
+		if i == g.slot || held[g.memberKey(i)] != "" {
+			members = append(members, i)
+		}
+	}
+	rank := sort.SearchInts(members, g.slot)
+	target := g.partitions / len(members)
+	if rank < g.partitions%len(members) {
+		target++
+	}
+
+	for _, partition := range g.Partitions() {
+		err := g.leases.Acquire(ctx, g.partitionKey(partition), g.owner, g.leaseDuration)
+		if IsLeaseHeld(err) {
+			g.logf("Member, %v, lost partition %v", g.owner, partition)
+			g.stopWorker(partition)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to renew lease on partition %v", partition)
+		}
+	}
+
+	g.shed(ctx, target)
+
+	for partition := 0; partition < g.partitions && len(g.Partitions()) < target; partition++ {
+		if g.owns(partition) {
+			continue
+		}
+		if owner := held[g.partitionKey(partition)]; owner != "" && owner != g.owner {
+			continue
+		}
+
+		err := g.leases.Acquire(ctx, g.partitionKey(partition), g.owner, g.leaseDuration)
+		if IsLeaseHeld(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to acquire lease on partition %v", partition)
+		}
+
+		g.logf("Member, %v, acquired partition %v", g.owner, partition)
+		g.startWorker(ctx, partition, failed)
+	}
+
+	return nil
+}
+
+// join renews this member's slot, or claims a free slot if it has none
+func (g *Group) join(ctx context.Context, held map[string]string) error {
+	if g.slot >= 0 {
+		err := g.leases.Acquire(ctx, g.memberKey(g.slot), g.owner, g.leaseDuration)
+		if err == nil {
+			return nil
+		}
+		if !IsLeaseHeld(err) {
+			return errors.Wrapf(err, "unable to renew membership of group, %v", g.name)
+		}
+		g.logf("Member, %v, lost slot %v", g.owner, g.slot)
+		g.slot = -1
+	}
+
+	for i := 0; i < g.partitions; i++ {
+		if owner := held[g.memberKey(i)]; owner != "" && owner != g.owner {
+			continue
+		}
+
+		err := g.leases.Acquire(ctx, g.memberKey(i), g.owner, g.leaseDuration)
+		if IsLeaseHeld(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to join group, %v", g.name)
+		}
+
+		g.logf("Member, %v, joined group, %v, in slot %v", g.owner, g.name, i)
+		g.slot = i
+		return nil
+	}
+
+	return nil
+}
+
+// shed stops and releases partitions, highest first, until at most target remain
+func (g *Group) shed(ctx context.Context, target int) {
+	partitions := g.Partitions()
+	for i := len(partitions) - 1; i >= target; i-- {
+		partition := partitions[i]
+		g.stopWorker(partition)
+		if err := g.leases.Release(ctx, g.partitionKey(partition), g.owner); err != nil {
+			// the lease will expire on its own
+			g.logf("Member, %v, unable to release partition %v: %v", g.owner, partition, err)
+		}
+		g.logf("Member, %v, released partition %v", g.owner, partition)
+	}
+}
+
+// leave stops all partitions and releases every lease held by this member
+func (g *Group) leave() {
+	ctx := context.Background()
+	g.shed(ctx, 0)
+	if g.slot >= 0 {
+		g.leases.Release(ctx, g.memberKey(g.slot), g.owner)
+		g.slot = -1
+	}
+}
+
+func (g *Group) owns(partition int) bool {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	_, ok := g.workers[partition]
+	return ok
+}
+
+func (g *Group) startWorker(ctx context.Context, partition int, failed chan error) {
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if Partition(record.AggregateID, g.partitions) != partition {
+			return nil
+		}
+		return g.handler.Handle(ctx, record)
+	})
+
+	opts := []subscription.Option{subscription.WithCheckpointStore(g.checkpoints)}
+	opts = append(opts, g.subscriptionOpts...)
+
+	w := &worker{
+		sub:  subscription.New(g.partitionKey(partition), g.reader, handler, opts...),
+		done: make(chan struct{}),
+	}
+
+	g.mux.Lock()
+	g.workers[partition] = w
+	g.mux.Unlock()
+
+	go func() {
+		defer close(w.done)
+		if err := w.sub.Run(ctx); err != nil && ctx.Err() == nil {
+			select {
+			case failed <- err:
+			default:
+			}
+		}
+	}()
+}
+
+func (g *Group) stopWorker(partition int) {
+	g.mux.Lock()
+	w, ok := g.workers[partition]
+	delete(g.workers, partition)
+	g.mux.Unlock()
+
+	if !ok {
+		return
+	}
+
+	w.sub.Stop()
+	<-w.done
+}
+
+func (g *Group) stopped() bool {
+	select {
+	case <-g.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop signals Run to release its partitions and return.  Stop blocks until Run has returned and is
+// safe to call more than once
+func (g *Group) Stop() {
+	g.mux.Lock()
+	if !g.stopped() {
+		close(g.stop)
+	}
+	running, done := g.running, g.done
+	g.mux.Unlock()
+
+	if running {
+		<-done
+	}
+}
+
+func defaultOwner() string {
+	hostname, _ := os.Hostname()
+	return hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(rand.Int63(), 36)
+}
+
+// New returns a new member of the named consumer group.  Records are delivered to handler by the
+// member that owns the record's partition
+func New(name string, reader eventsource.StreamReader, handler subscription.Handler, opts ...Option) *Group {
+	g := &Group{
+		name:          name,
+		reader:        reader,
+		handler:       handler,
+		partitions:    DefaultPartitions,
+		leases:        NewMemoryLeaseStore(),
+		checkpoints:   subscription.NewMemoryCheckpointStore(),
+		leaseDuration: DefaultLeaseDuration,
+		owner:         defaultOwner(),
+		slot:          -1,
+		workers:       map[int]*worker{},
+		stop:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
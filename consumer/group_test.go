@@ -0,0 +1,190 @@
+package consumer_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/consumer"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryReader provides a StreamReader over records for the specified number of aggregates
+type memoryReader struct {
+	records []eventsource.StreamRecord
+}
+
+func newMemoryReader(aggregates, versions int) *memoryReader {
+	m := &memoryReader{}
+	for version := 1; version <= versions; version++ {
+		for i := 0; i < aggregates; i++ {
+			m.records = append(m.records, eventsource.StreamRecord{
+				AggregateID: "id-" + strconv.Itoa(i),
+				Offset:      uint64(len(m.records) + 1),
+				Record:      eventsource.Record{Version: version},
+			})
+		}
+	}
+	return m
+}
+
+func (m *memoryReader) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	var records []eventsource.StreamRecord
+	for _, record := range m.records {
+		if record.Offset >= startingOffset && len(records) < recordCount {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// recorder captures the versions handled per aggregate
+type recorder struct {
+	mux      sync.Mutex
+	versions map[string][]int
+	count    int
+}
+
+func (r *recorder) Handle(ctx context.Context, record eventsource.StreamRecord) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.versions == nil {
+		r.versions = map[string][]int{}
+	}
+	r.versions[record.AggregateID] = append(r.versions[record.AggregateID], record.Version)
+	r.count++
+	return nil
+}
+
+func (r *recorder) Count() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.count
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	for i := 0; i < 200; i++ {
+		if fn() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
+
+func TestPartition(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		p := consumer.Partition(id, 8)
+		assert.True(t, p >= 0 && p < 8)
+		assert.Equal(t, p, consumer.Partition(id, 8))
+	}
+}
+
+func TestGroup(t *testing.T) {
+	const partitions = 4
+
+	ctx := context.Background()
+	reader := newMemoryReader(20, 5)
+	leases := consumer.NewMemoryLeaseStore()
+	checkpoints := subscription.NewMemoryCheckpointStore()
+	handler := &recorder{}
+
+	newMember := func(owner string) *consumer.Group {
+		return consumer.New("sample", reader, handler,
+			consumer.WithOwner(owner),
+			consumer.WithPartitions(partitions),
+			consumer.WithLeaseStore(leases),
+			consumer.WithCheckpointStore(checkpoints),
+			consumer.WithLeaseDuration(60*time.Millisecond),
+			consumer.WithSubscriptionOptions(
+				subscription.WithBatchSize(7),
+				subscription.WithPollInterval(5*time.Millisecond),
+			),
+		)
+	}
+
+	a := newMember("a")
+	doneA := make(chan error, 1)
+	go func() { doneA <- a.Run(ctx) }()
+
+	// a single member owns every partition and handles every record exactly once
+	waitFor(t, func() bool { return len(a.Partitions()) == partitions })
+	waitFor(t, func() bool { return handler.Count() == len(reader.records) })
+
+	// a second member takes a fair share of the partitions
+	b := newMember("b")
+	doneB := make(chan error, 1)
+	go func() { doneB <- b.Run(ctx) }()
+
+	waitFor(t, func() bool {
+		return len(a.Partitions()) == partitions/2 && len(b.Partitions()) == partitions/2
+	})
+	assert.Equal(t, len(reader.records), handler.Count())
+
+	// once b leaves, a takes its partitions back
+	b.Stop()
+	assert.Nil(t, <-doneB)
+	waitFor(t, func() bool { return len(a.Partitions()) == partitions })
+
+	a.Stop()
+	assert.Nil(t, <-doneA)
+
+	leased, err := leases.Leases(ctx, "sample/partition/0", "sample/member/0")
+	assert.Nil(t, err)
+	assert.Len(t, leased, 0)
+
+	// checkpoints are shared so no record is handled twice and order is kept per aggregate
+	assert.Equal(t, len(reader.records), handler.Count())
+	for id, versions := range handler.versions {
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, versions, id)
+	}
+}
+
+func TestGroup_HandlerError(t *testing.T) {
+	reader := newMemoryReader(2, 1)
+	boom := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		return assert.AnError
+	})
+
+	g := consumer.New("sample", reader, boom,
+		consumer.WithPartitions(1),
+		consumer.WithLeaseDuration(30*time.Millisecond),
+		consumer.WithSubscriptionOptions(subscription.WithPollInterval(time.Millisecond)),
+	)
+
+	err := g.Run(context.Background())
+	assert.NotNil(t, err)
+	assert.Len(t, g.Partitions(), 0)
+}
+
+func TestGroup_RunAgain(t *testing.T) {
+	reader := newMemoryReader(2, 1)
+	handler := &recorder{}
+
+	g := consumer.New("sample", reader, handler,
+		consumer.WithPartitions(1),
+		consumer.WithLeaseDuration(30*time.Millisecond),
+		consumer.WithSubscriptionOptions(subscription.WithPollInterval(time.Millisecond)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- g.Run(ctx) }()
+	waitFor(t, func() bool { return handler.Count() == 2 })
+
+	assert.NotNil(t, g.Run(context.Background()), "concurrent runs should be rejected")
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	// a subsequent run is permitted once the previous one has returned
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, g.Run(ctx))
+}
@@ -0,0 +1,146 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/singleton"
+)
+
+const (
+	// ErrLeaseHeld is returned when a lease is held by another owner and has not yet expired
+	ErrLeaseHeld = "LeaseHeld"
+)
+
+// Lease describes the current holder of a key
+type Lease struct {
+	Key       string
+	Owner     string
+	ExpiresAt time.Time
+}
+
+// LeaseStore coordinates ownership of keys between the members of a group.  Leases are advisory;
+// they provide no fencing so a holder whose lease has expired is not prevented from acting
+type LeaseStore interface {
+	// Acquire takes or renews the lease on key for the owner.  Acquire succeeds if the key is
+	// unleased, already held by owner, or the previous lease has expired; otherwise an error
+	// with code ErrLeaseHeld is returned
+	Acquire(ctx context.Context, key, owner string, d time.Duration) error
+
+	// Release gives up the lease on key if it is held by owner
+	Release(ctx context.Context, key, owner string) error
+
+	// Leases returns the unexpired leases held against the specified keys
+	Leases(ctx context.Context, keys ...string) ([]Lease, error)
+}
+
+// IsLeaseHeld returns true if the error indicates the lease is held by someone else
+func IsLeaseHeld(err error) bool {
+	return eventsource.ErrHasCode(err, ErrLeaseHeld)
+}
+
+type memoryLeaseStore struct {
+	mux    sync.Mutex
+	leases map[string]Lease
+}
+
+func (m *memoryLeaseStore) Acquire(ctx context.Context, key, owner string, d time.Duration) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := time.Now()
+	if lease, ok := m.leases[key]; ok && lease.Owner != owner && lease.ExpiresAt.After(now) {
+		return eventsource.NewError(nil, ErrLeaseHeld, "lease, %v, is held by %v", key, lease.Owner)
+	}
+
+	m.leases[key] = Lease{
+		Key:       key,
+		Owner:     owner,
+		ExpiresAt: now.Add(d),
+	}
+	return nil
+}
+
+func (m *memoryLeaseStore) Release(ctx context.Context, key, owner string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if lease, ok := m.leases[key]; ok && lease.Owner == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+func (m *memoryLeaseStore) Leases(ctx context.Context, keys ...string) ([]Lease, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := time.Now()
+	var leases []Lease
+	for _, key := range keys {
+		if lease, ok := m.leases[key]; ok && lease.ExpiresAt.After(now) {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+// NewMemoryLeaseStore returns an in-memory LeaseStore suitable for testing or for groups whose
+// members all live in the same process
+func NewMemoryLeaseStore() LeaseStore {
+	return &memoryLeaseStore{
+		leases: map[string]Lease{},
+	}
+}
+
+// leaseResourceType is the singleton.Resource type under which leases are reserved
+const leaseResourceType = "lease"
+
+type registryLeaseStore struct {
+	registry *singleton.Registry
+}
+
+func (r registryLeaseStore) Acquire(ctx context.Context, key, owner string, d time.Duration) error {
+	resource := singleton.Resource{Type: leaseResourceType, ID: key, Owner: owner}
+	if err := r.registry.Renew(ctx, resource, d); err != nil {
+		if singleton.IsAlreadyReserved(err) {
+			return eventsource.NewError(err, ErrLeaseHeld, "lease, %v, is held by another owner", key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r registryLeaseStore) Release(ctx context.Context, key, owner string) error {
+	return r.registry.Relinquish(ctx, singleton.Resource{Type: leaseResourceType, ID: key, Owner: owner})
+}
+
+func (r registryLeaseStore) Leases(ctx context.Context, keys ...string) ([]Lease, error) {
+	resources := make([]singleton.Resource, 0, len(keys))
+	for _, key := range keys {
+		resources = append(resources, singleton.Resource{Type: leaseResourceType, ID: key})
+	}
+
+	reservations, err := r.registry.Reservations(ctx, resources...)
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]Lease, 0, len(reservations))
+	for _, reservation := range reservations {
+		leases = append(leases, Lease{
+			Key:       reservation.ID,
+			Owner:     reservation.Owner,
+			ExpiresAt: reservation.ExpiresAt,
+		})
+	}
+	return leases, nil
+}
+
+// NewRegistryLeaseStore returns a LeaseStore backed by the dynamodb table of a singleton.Registry.
+// Leases are stored as reservations alongside any other resources held in the table
+func NewRegistryLeaseStore(registry *singleton.Registry) LeaseStore {
+	return registryLeaseStore{registry: registry}
+}
@@ -0,0 +1,40 @@
+package consumer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	leases := consumer.NewMemoryLeaseStore()
+
+	assert.Nil(t, leases.Acquire(ctx, "key", "a", time.Hour))
+	assert.Nil(t, leases.Acquire(ctx, "key", "a", time.Hour))
+
+	err := leases.Acquire(ctx, "key", "b", time.Hour)
+	assert.True(t, consumer.IsLeaseHeld(err))
+
+	// releasing a lease held by someone else has no effect
+	assert.Nil(t, leases.Release(ctx, "key", "b"))
+	found, err := leases.Leases(ctx, "key", "other")
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "a", found[0].Owner)
+
+	// expired leases may be taken over
+	assert.Nil(t, leases.Acquire(ctx, "key", "a", -time.Second))
+	found, err = leases.Leases(ctx, "key")
+	assert.Nil(t, err)
+	assert.Len(t, found, 0)
+
+	assert.Nil(t, leases.Acquire(ctx, "key", "b", time.Hour))
+	assert.Nil(t, leases.Release(ctx, "key", "b"))
+	found, err = leases.Leases(ctx, "key")
+	assert.Nil(t, err)
+	assert.Len(t, found, 0)
+}
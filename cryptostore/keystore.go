@@ -0,0 +1,105 @@
+package cryptostore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/altairsix/eventsource"
+)
+
+const (
+	// ErrKeyNotFound is returned by a KeyStore when no key has been created for the aggregate
+	ErrKeyNotFound = "KeyNotFound"
+
+	// ErrKeyExists is returned by a KeyStore when a key has already been created for the aggregate
+	ErrKeyExists = "KeyExists"
+
+	// ErrErased is returned when the key for an aggregate has been destroyed and its records can
+	// no longer be decrypted
+	ErrErased = "Erased"
+)
+
+// KeyStore holds the data key for each aggregate
+type KeyStore interface {
+	// GetKey returns the data key for the aggregate.  An error with code ErrKeyNotFound is returned
+	// if no key has been created and, where the KeyStore is able to tell, an error with code
+	// ErrErased is returned if the key has been deleted
+	GetKey(ctx context.Context, aggregateID string) ([]byte, error)
+
+	// PutKey stores the data key for the aggregate provided one does not already exist; otherwise
+	// an error with code ErrKeyExists is returned
+	PutKey(ctx context.Context, aggregateID string, key []byte) error
+
+	// DeleteKey irrecoverably destroys the data key for the aggregate
+	DeleteKey(ctx context.Context, aggregateID string) error
+}
+
+// IsKeyNotFound returns true if no key has been created for the aggregate
+func IsKeyNotFound(err error) bool {
+	return eventsource.ErrHasCode(err, ErrKeyNotFound)
+}
+
+// IsKeyExists returns true if a key had already been created for the aggregate
+func IsKeyExists(err error) bool {
+	return eventsource.ErrHasCode(err, ErrKeyExists)
+}
+
+// IsErased returns true if the aggregate has been erased
+func IsErased(err error) bool {
+	return eventsource.ErrHasCode(err, ErrErased)
+}
+
+type memoryKeyStore struct {
+	mux    sync.Mutex
+	keys   map[string][]byte
+	erased map[string]struct{}
+}
+
+func (m *memoryKeyStore) GetKey(ctx context.Context, aggregateID string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := m.erased[aggregateID]; ok {
+		return nil, eventsource.NewError(nil, ErrErased, "aggregate, %v, has been erased", aggregateID)
+	}
+
+	key, ok := m.keys[aggregateID]
+	if !ok {
+		return nil, eventsource.NewError(nil, ErrKeyNotFound, "no key found for aggregate, %v", aggregateID)
+	}
+
+	return append([]byte(nil), key...), nil
+}
+
+func (m *memoryKeyStore) PutKey(ctx context.Context, aggregateID string, key []byte) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := m.erased[aggregateID]; ok {
+		return eventsource.NewError(nil, ErrErased, "aggregate, %v, has been erased", aggregateID)
+	}
+	if _, ok := m.keys[aggregateID]; ok {
+		return eventsource.NewError(nil, ErrKeyExists, "key already exists for aggregate, %v", aggregateID)
+	}
+
+	m.keys[aggregateID] = append([]byte(nil), key...)
+	return nil
+}
+
+func (m *memoryKeyStore) DeleteKey(ctx context.Context, aggregateID string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.keys, aggregateID)
+	m.erased[aggregateID] = struct{}{}
+	return nil
+}
+
+// NewMemoryKeyStore returns a KeyStore that holds keys in memory; useful for testing.  Deleted
+// keys are remembered so that GetKey reports the aggregate as erased
+func NewMemoryKeyStore() KeyStore {
+	return &memoryKeyStore{
+		keys:   map[string][]byte{},
+		erased: map[string]struct{}{},
+	}
+}
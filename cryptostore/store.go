@@ -0,0 +1,281 @@
+package cryptostore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeySize is the size in bytes of the AES-256 data keys generated for each aggregate
+	KeySize = 32
+
+	// formatVersion prefixes each encrypted Record.Data
+	formatVersion byte = 1
+)
+
+// Store wraps an eventsource.Store and encrypts the Data of each record with a key unique to its
+// aggregate.  Deleting the key from the KeyStore, via Erase, renders the aggregate's records
+// unreadable without modifying the underlying store; Load then returns an error with code
+// ErrErased.
+//
+// Only Record.Data is encrypted; versions and metadata are stored as provided.  Nonces are derived
+// from the key and the record so that saving the same record twice produces the same ciphertext,
+// allowing the underlying store to recognize idempotent saves
+type Store struct {
+	store eventsource.Store
+	keys  KeyStore
+}
+
+// New returns a Store that encrypts records before saving them to store
+func New(store eventsource.Store, keys KeyStore) *Store {
+	return &Store{
+		store: store,
+		keys:  keys,
+	}
+}
+
+// Save encrypts and saves the records, creating a key for the aggregate if it does not yet have one
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	encrypted, err := s.encrypt(ctx, aggregateID, records)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Save(ctx, aggregateID, encrypted...)
+}
+
+// SaveVersion encrypts and saves the records provided the aggregate is at expectedVersion; implements
+// eventsource.VersionedStore.  If the underlying store is not a VersionedStore, SaveVersion falls
+// back to Save
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	store, ok := s.store.(eventsource.VersionedStore)
+	if !ok {
+		return s.Save(ctx, aggregateID, records...)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	encrypted, err := s.encrypt(ctx, aggregateID, records)
+	if err != nil {
+		return err
+	}
+
+	return store.SaveVersion(ctx, aggregateID, expectedVersion, encrypted...)
+}
+
+// SaveAll encrypts and saves several batches atomically; implements eventsource.BatchStore.  An
+// error with code ErrUnsupported is returned if the underlying store is not a BatchStore
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	store, ok := s.store.(eventsource.BatchStore)
+	if !ok {
+		return eventsource.NewError(nil, eventsource.ErrUnsupported, "store, %T, is unable to save multiple aggregates atomically", s.store)
+	}
+
+	encrypted := make([]eventsource.Batch, 0, len(batches))
+	for _, batch := range batches {
+		records, err := s.encrypt(ctx, batch.AggregateID, batch.Records)
+		if err != nil {
+			return err
+		}
+		encrypted = append(encrypted, eventsource.Batch{AggregateID: batch.AggregateID, Records: records})
+	}
+
+	return store.SaveAll(ctx, encrypted...)
+}
+
+// Load and decrypt the history of the aggregate.  If the aggregate has been erased, an error with
+// code ErrErased is returned
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	history, err := s.store.Load(ctx, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return history, nil
+	}
+
+	sealer, err := s.cipher(ctx, aggregateID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make(eventsource.History, 0, len(history))
+	for _, record := range history {
+		data, err := sealer.open(aggregateID, record)
+		if err != nil {
+			return nil, err
+		}
+		record.Data = data
+		decrypted = append(decrypted, record)
+	}
+
+	return decrypted, nil
+}
+
+// Read implements eventsource.StreamReader.  Records belonging to erased aggregates are returned
+// with nil Data so that readers may skip them without losing their place in the stream.  An error
+// with code ErrUnsupported is returned if the underlying store is not a StreamReader
+func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	reader, ok := s.store.(eventsource.StreamReader)
+	if !ok {
+		return nil, eventsource.NewError(nil, eventsource.ErrUnsupported, "store, %T, is unable to read the event stream", s.store)
+	}
+
+	records, err := reader.Read(ctx, startingOffset, recordCount)
+	if err != nil {
+		return nil, err
+	}
+
+	sealers := map[string]*sealer{}
+	for i, record := range records {
+		sealer, ok := sealers[record.AggregateID]
+		if !ok {
+			sealer, err = s.cipher(ctx, record.AggregateID, false)
+			if err != nil && !IsErased(err) {
+				return nil, err
+			}
+			sealers[record.AggregateID] = sealer
+		}
+
+		if sealer == nil {
+			records[i].Data = nil
+			continue
+		}
+
+		data, err := sealer.open(record.AggregateID, record.Record)
+		if err != nil {
+			return nil, err
+		}
+		records[i].Data = data
+	}
+
+	return records, nil
+}
+
+// Erase destroys the key for the aggregate.  The aggregate's records remain in the underlying
+// store but can no longer be decrypted
+func (s *Store) Erase(ctx context.Context, aggregateID string) error {
+	if err := s.keys.DeleteKey(ctx, aggregateID); err != nil {
+		return errors.Wrapf(err, "unable to erase aggregate, %v", aggregateID)
+	}
+	return nil
+}
+
+// sealer encrypts and decrypts the records of a single aggregate
+type sealer struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// cipher returns the sealer for the aggregate's key.  When create is true, a key is generated if
+// the aggregate does not yet have one.  A key that cannot be found for an aggregate with records is
+// reported as erased
+func (s *Store) cipher(ctx context.Context, aggregateID string, create bool) (*sealer, error) {
+	key, err := s.keys.GetKey(ctx, aggregateID)
+	if IsKeyNotFound(err) {
+		if !create {
+			return nil, eventsource.NewError(err, ErrErased, "aggregate, %v, has been erased", aggregateID)
+		}
+
+		key = make([]byte, KeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, errors.Wrap(err, "unable to generate key")
+		}
+
+		err = s.keys.PutKey(ctx, aggregateID, key)
+		if IsKeyExists(err) {
+			// another writer created the key first
+			key, err = s.keys.GetKey(ctx, aggregateID)
+		}
+	}
+	if err != nil {
+		if IsErased(err) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(err, "unable to retrieve key for aggregate, %v", aggregateID)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key for aggregate, %v", aggregateID)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create cipher for aggregate, %v", aggregateID)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("nonce"))
+
+	return &sealer{
+		aead:     aead,
+		nonceKey: mac.Sum(nil),
+	}, nil
+}
+
+func (s *sealer) seal(aggregateID string, record eventsource.Record) []byte {
+	ad := additionalData(aggregateID, record.Version)
+
+	mac := hmac.New(sha256.New, s.nonceKey)
+	mac.Write(ad)
+	mac.Write(record.Data)
+	nonce := mac.Sum(nil)[:s.aead.NonceSize()]
+
+	data := make([]byte, 0, 1+len(nonce)+len(record.Data)+s.aead.Overhead())
+	data = append(data, formatVersion)
+	data = append(data, nonce...)
+	return s.aead.Seal(data, nonce, record.Data, ad)
+}
+
+func (s *sealer) open(aggregateID string, record eventsource.Record) ([]byte, error) {
+	data, size := record.Data, s.aead.NonceSize()
+	if len(data) < 1+size || data[0] != formatVersion {
+		return nil, errors.Errorf("version %v of aggregate, %v, is not encrypted", record.Version, aggregateID)
+	}
+
+	plain, err := s.aead.Open(nil, data[1:1+size], data[1+size:], additionalData(aggregateID, record.Version))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decrypt version %v of aggregate, %v", record.Version, aggregateID)
+	}
+
+	return plain, nil
+}
+
+func (s *Store) encrypt(ctx context.Context, aggregateID string, records []eventsource.Record) ([]eventsource.Record, error) {
+	sealer, err := s.cipher(ctx, aggregateID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]eventsource.Record, 0, len(records))
+	for _, record := range records {
+		record.Data = sealer.seal(aggregateID, record)
+		encrypted = append(encrypted, record)
+	}
+
+	return encrypted, nil
+}
+
+// additionalData binds the ciphertext to the aggregate and version it was saved as so records cannot
+// be swapped between aggregates or reordered
+func additionalData(aggregateID string, version int) []byte {
+	buf := make([]byte, 8, 8+len(aggregateID))
+	binary.BigEndian.PutUint64(buf, uint64(version))
+	return append(buf, aggregateID...)
+}
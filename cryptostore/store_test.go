@@ -0,0 +1,289 @@
+package cryptostore_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/cryptostore"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is a minimal store that, like the real stores, treats an identical resave as a no-op
+// and any other reuse of a version as a conflict
+type memoryStore struct {
+	mux     sync.Mutex
+	history map[string]eventsource.History
+	stream  []eventsource.StreamRecord
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{history: map[string]eventsource.History{}}
+}
+
+func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.save(aggregateID, records)
+}
+
+func (m *memoryStore) save(aggregateID string, records []eventsource.Record) error {
+	history := m.history[aggregateID]
+	var added []eventsource.Record
+	for _, record := range records {
+		if record.Version > len(history) {
+			added = append(added, record)
+			continue
+		}
+		if !reflect.DeepEqual(history[record.Version-1], record) {
+			return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "version %v of %v already exists", record.Version, aggregateID)
+		}
+	}
+
+	for _, record := range added {
+		m.history[aggregateID] = append(m.history[aggregateID], record)
+		m.stream = append(m.stream, eventsource.StreamRecord{
+			Record:      record,
+			Offset:      uint64(len(m.stream) + 1),
+			AggregateID: aggregateID,
+		})
+	}
+	return nil
+}
+
+func (m *memoryStore) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if v := len(m.history[aggregateID]); v != expectedVersion {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "%v is at version %v", aggregateID, v)
+	}
+	return m.save(aggregateID, records)
+}
+
+func (m *memoryStore) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, batch := range batches {
+		if err := m.save(batch.AggregateID, batch.Records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	history := eventsource.History{}
+	for _, record := range m.history[aggregateID] {
+		if record.Version >= fromVersion && (toVersion == 0 || record.Version <= toVersion) {
+			history = append(history, record)
+		}
+	}
+	return history, nil
+}
+
+func (m *memoryStore) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	records := []eventsource.StreamRecord{}
+	for _, record := range m.stream {
+		if record.Offset >= startingOffset && len(records) < recordCount {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// loader provides nothing beyond eventsource.Store
+type loader struct {
+	eventsource.Store
+}
+
+func WithStore(t *testing.T, callback func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore)) {
+	inner := newMemoryStore()
+	keys := cryptostore.NewMemoryKeyStore()
+	callback(inner, cryptostore.New(inner, keys), keys)
+}
+
+func TestStore_Implements(t *testing.T) {
+	var store interface{} = cryptostore.New(nil, nil)
+
+	_, ok := store.(eventsource.VersionedStore)
+	assert.True(t, ok)
+
+	_, ok = store.(eventsource.BatchStore)
+	assert.True(t, ok)
+
+	_, ok = store.(eventsource.StreamReader)
+	assert.True(t, ok)
+}
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+		history := eventsource.History{
+			{Version: 1, Data: []byte("alice@example.com")},
+			{Version: 2, Data: []byte("bob@example.com")},
+		}
+
+		err := store.Save(ctx, "abc", history...)
+		assert.Nil(t, err)
+
+		raw, err := inner.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, raw, 2)
+		for i, record := range raw {
+			assert.Equal(t, history[i].Version, record.Version)
+			assert.False(t, bytes.Contains(record.Data, history[i].Data), "data should be encrypted")
+		}
+
+		found, err := store.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+
+		err = store.Save(ctx, "abc", history...)
+		assert.Nil(t, err, "saving the same records again should remain idempotent")
+
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 2, Data: []byte("eve@example.com")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+	})
+}
+
+func TestStore_SaveVersion(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+
+		err := store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+
+		err = store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		found, err := store.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, eventsource.History{{Version: 1, Data: []byte("a")}}, found)
+	})
+}
+
+func TestStore_Erase(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+
+		err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+		err = store.Save(ctx, "def", eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		err = store.Erase(ctx, "abc")
+		assert.Nil(t, err)
+
+		_, err = store.Load(ctx, "abc", 0, 0)
+		assert.True(t, cryptostore.IsErased(err))
+
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 2, Data: []byte("c")})
+		assert.True(t, cryptostore.IsErased(err), "erased aggregates may not be written to")
+
+		found, err := store.Load(ctx, "def", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+
+		records, err := store.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "abc", records[0].AggregateID)
+		assert.Nil(t, records[0].Data)
+		assert.Equal(t, "def", records[1].AggregateID)
+		assert.Equal(t, []byte("b"), records[1].Data)
+	})
+}
+
+func TestStore_MissingKey(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+
+		err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+
+		// a KeyStore that does not remember deletions still reports erasure
+		other := cryptostore.New(inner, cryptostore.NewMemoryKeyStore())
+		_, err = other.Load(ctx, "abc", 0, 0)
+		assert.True(t, cryptostore.IsErased(err))
+
+		found, err := other.Load(ctx, "missing", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 0)
+	})
+}
+
+func TestStore_Tampered(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+
+		err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+
+		raw, err := inner.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+
+		// records may not be moved between aggregates
+		err = inner.Save(ctx, "def", raw...)
+		assert.Nil(t, err)
+		key, err := keys.GetKey(ctx, "abc")
+		assert.Nil(t, err)
+		err = keys.PutKey(ctx, "def", key)
+		assert.Nil(t, err)
+
+		_, err = store.Load(ctx, "def", 0, 0)
+		assert.NotNil(t, err)
+		assert.False(t, cryptostore.IsErased(err))
+	})
+}
+
+func TestStore_Unsupported(t *testing.T) {
+	WithStore(t, func(inner *memoryStore, store *cryptostore.Store, keys cryptostore.KeyStore) {
+		ctx := context.Background()
+		store = cryptostore.New(loader{Store: inner}, keys)
+
+		err := store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err, "SaveVersion should fall back to Save")
+
+		err = store.SaveAll(ctx, eventsource.Batch{AggregateID: "abc"})
+		assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+
+		_, err = store.Read(ctx, 0, 10)
+		assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+	})
+}
+
+func TestMemoryKeyStore(t *testing.T) {
+	ctx := context.Background()
+	keys := cryptostore.NewMemoryKeyStore()
+
+	_, err := keys.GetKey(ctx, "abc")
+	assert.True(t, cryptostore.IsKeyNotFound(err))
+
+	err = keys.PutKey(ctx, "abc", []byte("key"))
+	assert.Nil(t, err)
+
+	err = keys.PutKey(ctx, "abc", []byte("other"))
+	assert.True(t, cryptostore.IsKeyExists(err))
+
+	key, err := keys.GetKey(ctx, "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("key"), key)
+
+	err = keys.DeleteKey(ctx, "abc")
+	assert.Nil(t, err)
+
+	_, err = keys.GetKey(ctx, "abc")
+	assert.True(t, cryptostore.IsErased(err))
+}
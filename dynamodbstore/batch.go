@@ -0,0 +1,176 @@
+package dynamodbstore
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxTransactItems is the maximum number of items dynamodb allows within a single transaction
+	maxTransactItems = 100
+
+	// maxTransactAttempts is the number of times a transaction is attempted when it fails only
+	// because another writer advanced the stream table
+	maxTransactAttempts = 25
+
+	awsConditionalCheckFailedReason = "ConditionalCheckFailed"
+)
+
+// pendingBatch holds the records for a single aggregate along with the update that saves them
+type pendingBatch struct {
+	aggregateID string
+	records     eventsource.History
+	input       *dynamodb.UpdateItemInput
+}
+
+// SaveAll saves the records of several aggregates atomically using TransactWriteItems; implements
+// eventsource.BatchStore.  As with Save, the records for each aggregate must fit within a single
+// item and at most 100 items may be written in a single call
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	pending := make([]pendingBatch, 0, len(batches))
+	seen := map[string]struct{}{}
+	for _, batch := range batches {
+		if len(batch.Records) == 0 {
+			continue
+		}
+		if _, ok := seen[batch.AggregateID]; ok {
+			return errors.Errorf("SaveAll failed; aggregate, %v, may only appear once", batch.AggregateID)
+		}
+		seen[batch.AggregateID] = struct{}{}
+
+		records := append(eventsource.History(nil), batch.Records...)
+		sort.Sort(records)
+
+		input, err := makeUpdateItemInput(s.tableName, s.hashKey, s.rangeKey, s.eventsPerItem, batch.AggregateID, records...)
+		if err != nil {
+			return err
+		}
+
+		pending = append(pending, pendingBatch{aggregateID: batch.AggregateID, records: records, input: input})
+	}
+
+	return s.transact(ctx, pending...)
+}
+
+// transact saves the pending batches, and their stream table entries if configured, in a single
+// transaction
+func (s *Store) transact(ctx context.Context, pending ...pendingBatch) error {
+	// each aggregate is one item; with a stream table, each record is an additional item plus one
+	// for the stream counter
+	items := len(pending)
+	if s.streamTable != "" {
+		items++
+		for _, batch := range pending {
+			items += len(batch.records)
+		}
+	}
+	if items > maxTransactItems {
+		if s.streamTable != "" {
+			return errors.Errorf("save failed; %v aggregate(s) and their stream table entries require %v items, exceeding the maximum of %v items per transaction", len(pending), items, maxTransactItems)
+		}
+		return errors.Errorf("save failed; %v aggregate(s) exceeds the maximum of %v items per transaction", len(pending), maxTransactItems)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		input := &dynamodb.TransactWriteItemsInput{}
+		for _, batch := range pending {
+			input.TransactItems = append(input.TransactItems, &dynamodb.TransactWriteItem{
+				Update: &dynamodb.Update{
+					TableName:                 batch.input.TableName,
+					Key:                       batch.input.Key,
+					ConditionExpression:       batch.input.ConditionExpression,
+					UpdateExpression:          batch.input.UpdateExpression,
+					ExpressionAttributeNames:  batch.input.ExpressionAttributeNames,
+					ExpressionAttributeValues: batch.input.ExpressionAttributeValues,
+				},
+			})
+		}
+
+		if s.streamTable != "" {
+			last, err := s.lastOffset(ctx)
+			if err != nil {
+				return err
+			}
+			input.TransactItems = append(input.TransactItems, makeStreamItems(s.streamTable, last, pending...)...)
+		}
+
+		if s.debug {
+			encoder := json.NewEncoder(s.writer)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(input)
+		}
+
+		_, err := s.api.TransactWriteItemsWithContext(ctx, input)
+		if err == nil {
+			return nil
+		}
+
+		v, ok := err.(*dynamodb.TransactionCanceledException)
+		if !ok {
+			if v, ok := err.(awserr.Error); ok {
+				return errors.Wrapf(err, "save failed. %v [%v]", v.Message(), v.Code())
+			}
+			return err
+		}
+
+		// the transaction was cancelled; batches whose records were already saved are dropped,
+		// any other conflict fails the save
+		remaining := pending[:0:0]
+		for i, batch := range pending {
+			if isConditionalCheckFailed(v, i) {
+				if err := s.checkIdempotent(ctx, batch.aggregateID, batch.records...); err != nil {
+					return err
+				}
+				continue
+			}
+			remaining = append(remaining, batch)
+		}
+
+		if len(remaining) == len(pending) {
+			// the stream table was advanced by another writer between reading and writing the offset
+			if s.streamTable != "" && isConditionalCheckFailed(v, len(pending)) {
+				if delay, ok := streamBackoff.Backoff(attempt); ok {
+					if err := sleep(ctx, delay); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			return errors.Wrapf(err, "save failed. %v", v.Message())
+		}
+		pending = remaining
+	}
+
+	return nil
+}
+
+// streamBackoff spaces out the attempts of writers contending for the stream counter
+var streamBackoff = eventsource.ExponentialBackoff(maxTransactAttempts, 5*time.Millisecond, 250*time.Millisecond)
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isConditionalCheckFailed returns true if the transact item at index i failed its condition
+func isConditionalCheckFailed(err *dynamodb.TransactionCanceledException, i int) bool {
+	if i >= len(err.CancellationReasons) {
+		return false
+	}
+	reason := err.CancellationReasons[i]
+	return reason != nil && reason.Code != nil && *reason.Code == awsConditionalCheckFailedReason
+}
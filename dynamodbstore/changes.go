@@ -45,8 +45,9 @@ func Changes(record *apex.Record) ([]eventsource.Record, error) {
 		data := record.Dynamodb.NewImage[key].B
 
 		items = append(items, eventsource.Record{
-			Version: version,
-			Data:    data,
+			Version:  version,
+			Data:     data,
+			Metadata: metadataFromItem(record.Dynamodb.NewImage, version),
 		})
 	}
 
@@ -6,6 +6,7 @@ import (
 	"github.com/altairsix/eventsource"
 	"github.com/altairsix/eventsource/dynamodbstore"
 	apex "github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,6 +40,32 @@ func TestRawEvents(t *testing.T) {
 				},
 			},
 		},
+		"metadata": {
+			Record: &apex.Record{
+				Dynamodb: &apex.StreamRecord{
+					NewImage: map[string]*dynamodb.AttributeValue{
+						"_1":  {B: []byte("a")},
+						"_2":  {B: []byte("b")},
+						"m_2": {M: map[string]*dynamodb.AttributeValue{"actor": {S: aws.String("joe")}}},
+						"_3":  {B: []byte("c")},
+					},
+					OldImage: map[string]*dynamodb.AttributeValue{
+						"_1": {B: []byte("a")},
+					},
+				},
+			},
+			Expected: []eventsource.Record{
+				{
+					Version:  2,
+					Data:     []byte("b"),
+					Metadata: eventsource.Metadata{"actor": "joe"},
+				},
+				{
+					Version: 3,
+					Data:    []byte("c"),
+				},
+			},
+		},
 	}
 
 	for label, tc := range testCases {
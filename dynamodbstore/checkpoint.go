@@ -0,0 +1,80 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CheckpointOffsetField is the field that holds the offset of the subscription
+	CheckpointOffsetField = "offset"
+)
+
+// CheckpointStore records the progress of subscriptions in dynamodb; implements
+// subscription.CheckpointStore.  Each subscription has a single item keyed by its name
+type CheckpointStore struct {
+	tableName string
+	hashKey   string
+	api       *dynamodb.DynamoDB
+}
+
+// SaveCheckpoint saves the offset of the next record to be read by the named subscription
+func (c *CheckpointStore) SaveCheckpoint(ctx context.Context, name string, offset uint64) error {
+	_, err := c.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			c.hashKey:             {S: aws.String(name)},
+			CheckpointOffsetField: {N: aws.String(strconv.FormatUint(offset, 10))},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to save checkpoint for subscription, %v", name)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint returns the offset saved for the named subscription; 0 if none has been saved
+func (c *CheckpointStore) LoadCheckpoint(ctx context.Context, name string) (uint64, error) {
+	out, err := c.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			c.hashKey: {S: aws.String(name)},
+		},
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to load checkpoint for subscription, %v", name)
+	}
+
+	av, ok := out.Item[CheckpointOffsetField]
+	if !ok || av.N == nil {
+		return 0, nil
+	}
+
+	offset, err := strconv.ParseUint(*av.N, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid checkpoint for subscription, %v", name)
+	}
+
+	return offset, nil
+}
+
+// NewCheckpointStore constructs a new dynamodb backed checkpoint store.  Accepts the same options as
+// New
+func NewCheckpointStore(tableName string, opts ...Option) (*CheckpointStore, error) {
+	store, err := New(tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointStore{
+		tableName: tableName,
+		hashKey:   store.hashKey,
+		api:       store.api,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package dynamodbstore_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointStore_ImplementsCheckpointStore(t *testing.T) {
+	v, err := dynamodbstore.NewCheckpointStore("blah")
+	assert.Nil(t, err)
+
+	var store subscription.CheckpointStore = v
+	assert.NotNil(t, store)
+}
+
+func TestCheckpointStore_SaveAndLoad(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	tableName := "checkpoints-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	_, err = api.CreateTable(dynamodbstore.MakeCreateCheckpointTableInput(tableName, 50, 50))
+	assert.Nil(t, err)
+	defer api.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+
+	ctx := context.Background()
+	store, err := dynamodbstore.NewCheckpointStore(tableName,
+		dynamodbstore.WithDynamoDB(api),
+	)
+	assert.Nil(t, err)
+
+	offset, err := store.LoadCheckpoint(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), offset)
+
+	err = store.SaveCheckpoint(ctx, "sample", 20)
+	assert.Nil(t, err)
+
+	offset, err = store.LoadCheckpoint(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(20), offset)
+}
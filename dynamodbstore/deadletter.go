@@ -0,0 +1,260 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DeadLetterHashKey is the hash key of the dead letter table; holds the subscription name
+	DeadLetterHashKey = "subscription"
+
+	// DeadLetterRangeKey is the range key of the dead letter table; holds the offset of the record
+	DeadLetterRangeKey = "offset"
+
+	// DeadLetterErrorField holds the error returned by the final attempt to handle the record
+	DeadLetterErrorField = "error"
+
+	// DeadLetterAttemptsField holds the number of attempts made to handle the record
+	DeadLetterAttemptsField = "attempts"
+
+	// DeadLetterFailedAtField holds the time of the final attempt in unix milliseconds
+	DeadLetterFailedAtField = "failed_at"
+
+	// DeadLetterRedriveField is set when the dead letter has been marked for redrive
+	DeadLetterRedriveField = "redrive"
+
+	// DeadLetterRedriveKey holds the subscription name only while the dead letter is marked for
+	// redrive; it is the hash key of the sparse DeadLetterRedriveIndex
+	DeadLetterRedriveKey = "redrive_subscription"
+
+	// DeadLetterRedriveIndex is the global secondary index holding only the dead letters marked
+	// for redrive
+	DeadLetterRedriveIndex = "redrive"
+)
+
+// DeadLetterStore holds records subscriptions were unable to handle in dynamodb; implements
+// subscription.DeadLetterStore
+type DeadLetterStore struct {
+	tableName string
+	api       *dynamodb.DynamoDB
+}
+
+func deadLetterKey(name string, offset uint64) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		DeadLetterHashKey:  {S: aws.String(name)},
+		DeadLetterRangeKey: {N: aws.String(strconv.FormatUint(offset, 10))},
+	}
+}
+
+// PutDeadLetter saves the dead letter, replacing any with the same subscription and offset
+func (d *DeadLetterStore) PutDeadLetter(ctx context.Context, letter subscription.DeadLetter) error {
+	item := deadLetterKey(letter.Subscription, letter.Record.Offset)
+	item[StreamAggregateIDField] = &dynamodb.AttributeValue{S: aws.String(letter.Record.AggregateID)}
+	item[StreamVersionField] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(letter.Record.Version))}
+	item[DeadLetterAttemptsField] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(letter.Attempts))}
+	item[DeadLetterFailedAtField] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(letter.FailedAt.UnixNano()/int64(time.Millisecond), 10))}
+	item[DeadLetterRedriveField] = &dynamodb.AttributeValue{BOOL: aws.Bool(letter.Redrive)}
+	if letter.Redrive {
+		item[DeadLetterRedriveKey] = &dynamodb.AttributeValue{S: aws.String(letter.Subscription)}
+	}
+	if len(letter.Record.Data) > 0 {
+		item[StreamDataField] = &dynamodb.AttributeValue{B: letter.Record.Data}
+	}
+	if letter.Error != "" {
+		item[DeadLetterErrorField] = &dynamodb.AttributeValue{S: aws.String(letter.Error)}
+	}
+	if av := metadataValue(letter.Record.Metadata); av != nil {
+		item[StreamMetadataField] = av
+	}
+
+	_, err := d.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to put dead letter at offset %v for subscription, %v", letter.Record.Offset, letter.Subscription)
+	}
+
+	return nil
+}
+
+// GetDeadLetter returns the dead letter for the record at offset
+func (d *DeadLetterStore) GetDeadLetter(ctx context.Context, name string, offset uint64) (subscription.DeadLetter, error) {
+	out, err := d.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key:            deadLetterKey(name, offset),
+	})
+	if err != nil {
+		return subscription.DeadLetter{}, errors.Wrapf(err, "unable to get dead letter at offset %v for subscription, %v", offset, name)
+	}
+	if len(out.Item) == 0 {
+		return subscription.DeadLetter{}, eventsource.NewError(nil, subscription.ErrDeadLetterNotFound, "no dead letter at offset %v for subscription, %v", offset, name)
+	}
+
+	return deadLetterFromItem(out.Item)
+}
+
+// ListDeadLetters returns the dead letters of the named subscription ordered by offset
+func (d *DeadLetterStore) ListDeadLetters(ctx context.Context, name string) ([]subscription.DeadLetter, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("#key = :key"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String(DeadLetterHashKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {S: aws.String(name)},
+		},
+	}
+
+	letters, err := d.query(ctx, input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list dead letters for subscription, %v", name)
+	}
+
+	return letters, nil
+}
+
+// ListRedrives returns the dead letters of the named subscription marked for redrive, ordered by
+// offset.  Only marked dead letters are read, using the sparse DeadLetterRedriveIndex
+func (d *DeadLetterStore) ListRedrives(ctx context.Context, name string) ([]subscription.DeadLetter, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		IndexName:              aws.String(DeadLetterRedriveIndex),
+		KeyConditionExpression: aws.String("#key = :key"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String(DeadLetterRedriveKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {S: aws.String(name)},
+		},
+	}
+
+	letters, err := d.query(ctx, input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list dead letters marked for redrive for subscription, %v", name)
+	}
+
+	// the index is eventually consistent; confirm each letter is still marked
+	marked := letters[:0]
+	for _, letter := range letters {
+		if letter.Redrive {
+			marked = append(marked, letter)
+		}
+	}
+
+	return marked, nil
+}
+
+func (d *DeadLetterStore) query(ctx context.Context, input *dynamodb.QueryInput) ([]subscription.DeadLetter, error) {
+	letters := []subscription.DeadLetter{}
+	var decodeErr error
+	err := d.api.QueryPagesWithContext(ctx, input, func(out *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range out.Items {
+			letter, err := deadLetterFromItem(item)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			letters = append(letters, letter)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	return letters, nil
+}
+
+// DeleteDeadLetter removes the dead letter for the record at offset
+func (d *DeadLetterStore) DeleteDeadLetter(ctx context.Context, name string, offset uint64) error {
+	_, err := d.api.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       deadLetterKey(name, offset),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to delete dead letter at offset %v for subscription, %v", offset, name)
+	}
+
+	return nil
+}
+
+func deadLetterFromItem(item map[string]*dynamodb.AttributeValue) (subscription.DeadLetter, error) {
+	number := func(field string) (int64, error) {
+		av, ok := item[field]
+		if !ok || av.N == nil {
+			return 0, nil
+		}
+		return strconv.ParseInt(*av.N, 10, 64)
+	}
+
+	offset, err := strconv.ParseUint(aws.StringValue(item[DeadLetterRangeKey].N), 10, 64)
+	if err != nil {
+		return subscription.DeadLetter{}, errors.Wrap(err, "invalid dead letter offset")
+	}
+	version, err := number(StreamVersionField)
+	if err != nil {
+		return subscription.DeadLetter{}, errors.Wrap(err, "invalid dead letter version")
+	}
+	attempts, err := number(DeadLetterAttemptsField)
+	if err != nil {
+		return subscription.DeadLetter{}, errors.Wrap(err, "invalid dead letter attempts")
+	}
+	failedAt, err := number(DeadLetterFailedAtField)
+	if err != nil {
+		return subscription.DeadLetter{}, errors.Wrap(err, "invalid dead letter failure time")
+	}
+
+	letter := subscription.DeadLetter{
+		Subscription: aws.StringValue(item[DeadLetterHashKey].S),
+		Record: eventsource.StreamRecord{
+			Offset:      offset,
+			AggregateID: aws.StringValue(item[StreamAggregateIDField].S),
+			Record: eventsource.Record{
+				Version:  int(version),
+				Metadata: metadataFromValue(item[StreamMetadataField]),
+			},
+		},
+		Attempts: int(attempts),
+		FailedAt: time.Unix(0, failedAt*int64(time.Millisecond)),
+	}
+	if av, ok := item[StreamDataField]; ok {
+		letter.Record.Data = av.B
+	}
+	if av, ok := item[DeadLetterErrorField]; ok {
+		letter.Error = aws.StringValue(av.S)
+	}
+	if av, ok := item[DeadLetterRedriveField]; ok {
+		letter.Redrive = aws.BoolValue(av.BOOL)
+	}
+
+	return letter, nil
+}
+
+// NewDeadLetterStore constructs a new dynamodb backed dead letter store.  Accepts the same options
+// as New
+func NewDeadLetterStore(tableName string, opts ...Option) (*DeadLetterStore, error) {
+	store, err := New(tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterStore{
+		tableName: tableName,
+		api:       store.api,
+	}, nil
+}
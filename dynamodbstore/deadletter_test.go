@@ -0,0 +1,96 @@
+package dynamodbstore_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterStore_ImplementsDeadLetterStore(t *testing.T) {
+	v, err := dynamodbstore.NewDeadLetterStore("blah")
+	assert.Nil(t, err)
+
+	var store subscription.DeadLetterStore = v
+	assert.NotNil(t, store)
+}
+
+func TestDeadLetterStore_Lifecycle(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	tableName := "deadletters-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	_, err = api.CreateTable(dynamodbstore.MakeCreateDeadLetterTableInput(tableName, 50, 50))
+	assert.Nil(t, err)
+	defer api.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+
+	ctx := context.Background()
+	store, err := dynamodbstore.NewDeadLetterStore(tableName,
+		dynamodbstore.WithDynamoDB(api),
+	)
+	assert.Nil(t, err)
+
+	_, err = store.GetDeadLetter(ctx, "sample", 1)
+	assert.True(t, subscription.IsDeadLetterNotFound(err))
+
+	letter := subscription.DeadLetter{
+		Subscription: "sample",
+		Record: eventsource.StreamRecord{
+			Offset:      3,
+			AggregateID: "abc",
+			Record: eventsource.Record{
+				Version:  2,
+				Data:     []byte("data"),
+				Metadata: eventsource.Metadata{eventsource.CorrelationIDKey: "123"},
+			},
+		},
+		Error:    "boom",
+		Attempts: 4,
+		FailedAt: time.Unix(1500000000, 0),
+	}
+	assert.Nil(t, store.PutDeadLetter(ctx, letter))
+
+	found, err := store.GetDeadLetter(ctx, "sample", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, letter, found)
+
+	letters, err := store.ListDeadLetters(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, []subscription.DeadLetter{letter}, letters)
+
+	letters, err = store.ListRedrives(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 0)
+
+	letter.Redrive = true
+	assert.Nil(t, store.PutDeadLetter(ctx, letter))
+
+	// the redrive index is eventually consistent
+	for i := 0; i < 50 && len(letters) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		letters, err = store.ListRedrives(ctx, "sample")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []subscription.DeadLetter{letter}, letters)
+
+	assert.Nil(t, store.DeleteDeadLetter(ctx, "sample", 3))
+	letters, err = store.ListDeadLetters(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 0)
+}
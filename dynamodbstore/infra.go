@@ -52,3 +52,133 @@ func MakeCreateTableInput(tableName string, readCapacity, writeCapacity int64, o
 
 	return input
 }
+
+// MakeCreateSnapshotTableInput is a utility tool to write the default table definition for creating the
+// snapshot table
+func MakeCreateSnapshotTableInput(tableName string, readCapacity, writeCapacity int64, opts ...Option) *dynamodb.CreateTableInput {
+	store := &Store{
+		region:    DefaultRegion,
+		tableName: tableName,
+		hashKey:   HashKey,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(store.hashKey),
+				AttributeType: aws.String("S"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(store.hashKey),
+				KeyType:       aws.String("HASH"),
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
+
+// MakeCreateCheckpointTableInput is a utility tool to write the default table definition for creating the
+// subscription checkpoint table
+func MakeCreateCheckpointTableInput(tableName string, readCapacity, writeCapacity int64, opts ...Option) *dynamodb.CreateTableInput {
+	return MakeCreateSnapshotTableInput(tableName, readCapacity, writeCapacity, opts...)
+}
+
+// MakeCreateStreamTableInput is a utility tool to write the default table definition for creating the
+// change-log table used by WithStreamTable
+func MakeCreateStreamTableInput(tableName string, readCapacity, writeCapacity int64) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(StreamHashKey),
+				AttributeType: aws.String("S"),
+			},
+			{
+				AttributeName: aws.String(StreamRangeKey),
+				AttributeType: aws.String("N"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(StreamHashKey),
+				KeyType:       aws.String("HASH"),
+			},
+			{
+				AttributeName: aws.String(StreamRangeKey),
+				KeyType:       aws.String("RANGE"),
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
+
+// MakeCreateDeadLetterTableInput is a utility tool to write the default table definition for creating the
+// subscription dead letter table
+func MakeCreateDeadLetterTableInput(tableName string, readCapacity, writeCapacity int64) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(DeadLetterHashKey),
+				AttributeType: aws.String("S"),
+			},
+			{
+				AttributeName: aws.String(DeadLetterRangeKey),
+				AttributeType: aws.String("N"),
+			},
+			{
+				AttributeName: aws.String(DeadLetterRedriveKey),
+				AttributeType: aws.String("S"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(DeadLetterHashKey),
+				KeyType:       aws.String("HASH"),
+			},
+			{
+				AttributeName: aws.String(DeadLetterRangeKey),
+				KeyType:       aws.String("RANGE"),
+			},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(DeadLetterRedriveIndex),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String(DeadLetterRedriveKey),
+						KeyType:       aws.String("HASH"),
+					},
+					{
+						AttributeName: aws.String(DeadLetterRangeKey),
+						KeyType:       aws.String("RANGE"),
+					},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType: aws.String("ALL"),
+				},
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(readCapacity),
+					WriteCapacityUnits: aws.Int64(writeCapacity),
+				},
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
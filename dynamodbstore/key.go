@@ -8,6 +8,9 @@ import (
 const (
 	// prefix prefixes the event keys in the dynamodb item
 	prefix = "_"
+
+	// metadataPrefix prefixes the event metadata keys in the dynamodb item
+	metadataPrefix = "m_"
 )
 
 func isKey(key string) bool {
@@ -18,6 +21,10 @@ func makeKey(version int) string {
 	return prefix + strconv.Itoa(version)
 }
 
+func makeMetadataKey(version int) string {
+	return metadataPrefix + strconv.Itoa(version)
+}
+
 func versionFromKey(key string) (int, error) {
 	if !strings.HasPrefix(key, prefix) {
 		return 0, errInvalidKey
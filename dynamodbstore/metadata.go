@@ -0,0 +1,40 @@
+package dynamodbstore
+
+import (
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// metadataValue converts the metadata into a dynamodb map attribute; returns nil if there is no metadata
+func metadataValue(metadata eventsource.Metadata) *dynamodb.AttributeValue {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*dynamodb.AttributeValue, len(metadata))
+	for k, v := range metadata {
+		m[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+
+	return &dynamodb.AttributeValue{M: m}
+}
+
+// metadataFromItem extracts the metadata for the specified version from the dynamodb item
+func metadataFromItem(item map[string]*dynamodb.AttributeValue, version int) eventsource.Metadata {
+	return metadataFromValue(item[makeMetadataKey(version)])
+}
+
+// metadataFromValue converts a dynamodb map attribute into metadata; returns nil if there is no metadata
+func metadataFromValue(av *dynamodb.AttributeValue) eventsource.Metadata {
+	if av == nil || len(av.M) == 0 {
+		return nil
+	}
+
+	metadata := make(eventsource.Metadata, len(av.M))
+	for k, v := range av.M {
+		metadata[k] = aws.StringValue(v.S)
+	}
+
+	return metadata
+}
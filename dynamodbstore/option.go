@@ -37,3 +37,19 @@ func WithDebug(w io.Writer) Option {
 		s.writer = w
 	}
 }
+
+// WithStreamTable causes every saved record to also be appended, within the same transaction, to the
+// specified change-log table.  The change-log assigns each record a global offset and allows the
+// Store to be used as an eventsource.StreamReader.  See MakeCreateStreamTableInput.
+//
+// Global offsets come at a cost: every save advances a single counter item, so saves across the
+// whole table are serialized and throughput is limited to what one dynamodb item sustains, in
+// practice tens of saves per second under contention.  Each save also becomes a transaction, and a
+// save that would exceed dynamodb's limit of 100 items per transaction (one per aggregate, one per
+// record and one for the counter) is rejected.  Prefer dynamodb streams where throughput matters
+// more than a global order
+func WithStreamTable(tableName string) Option {
+	return func(s *Store) {
+		s.streamTable = tableName
+	}
+}
@@ -0,0 +1,105 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SnapshotVersionField is the field that holds the version of the snapshot
+	SnapshotVersionField = "version"
+
+	// SnapshotDataField is the field that holds the serialized snapshot
+	SnapshotDataField = "data"
+)
+
+// SnapshotStore represents a dynamodb backed eventsource.SnapshotStore.  Each aggregate has
+// a single item containing its most recent snapshot
+type SnapshotStore struct {
+	tableName string
+	hashKey   string
+	api       *dynamodb.DynamoDB
+}
+
+// SaveSnapshot implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) SaveSnapshot(ctx context.Context, aggregateID string, snapshot eventsource.Snapshot) error {
+	_, err := s.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.hashKey:            {S: aws.String(aggregateID)},
+			SnapshotVersionField: {N: aws.String(strconv.Itoa(snapshot.Version))},
+			SnapshotDataField:    {B: snapshot.Data},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#version) OR #version < :version"),
+		ExpressionAttributeNames: map[string]*string{
+			"#version": aws.String(SnapshotVersionField),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":version": {N: aws.String(strconv.Itoa(snapshot.Version))},
+		},
+	})
+	if err != nil {
+		if v, ok := err.(awserr.Error); ok {
+			if v.Code() == awsConditionalCheckFailed {
+				// a more recent snapshot has already been saved
+				return nil
+			}
+			return errors.Wrapf(err, "SaveSnapshot failed. %v [%v]", v.Message(), v.Code())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// LoadSnapshot implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) LoadSnapshot(ctx context.Context, aggregateID string) (eventsource.Snapshot, error) {
+	out, err := s.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.hashKey: {S: aws.String(aggregateID)},
+		},
+	})
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+
+	if len(out.Item) == 0 {
+		return eventsource.Snapshot{}, eventsource.NewError(nil, eventsource.ErrSnapshotNotFound, "no snapshot found with id, %v", aggregateID)
+	}
+
+	snapshot := eventsource.Snapshot{}
+	if av, ok := out.Item[SnapshotVersionField]; ok && av.N != nil {
+		version, err := strconv.Atoi(*av.N)
+		if err != nil {
+			return eventsource.Snapshot{}, errors.Wrapf(err, "invalid snapshot version for aggregate, %v", aggregateID)
+		}
+		snapshot.Version = version
+	}
+	if av, ok := out.Item[SnapshotDataField]; ok {
+		snapshot.Data = av.B
+	}
+
+	return snapshot, nil
+}
+
+// NewSnapshotStore constructs a new dynamodb backed snapshot store.  Accepts the same options as New
+func NewSnapshotStore(tableName string, opts ...Option) (*SnapshotStore, error) {
+	store, err := New(tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SnapshotStore{
+		tableName: tableName,
+		hashKey:   store.hashKey,
+		api:       store.api,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package dynamodbstore_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStore_ImplementsSnapshotStore(t *testing.T) {
+	v, err := dynamodbstore.NewSnapshotStore("blah")
+	assert.Nil(t, err)
+
+	var store eventsource.SnapshotStore = v
+	assert.NotNil(t, store)
+}
+
+func TestSnapshotStore_SaveAndLoad(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	tableName := "snapshots-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	_, err = api.CreateTable(dynamodbstore.MakeCreateSnapshotTableInput(tableName, 50, 50))
+	assert.Nil(t, err)
+	defer api.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+
+	ctx := context.Background()
+	store, err := dynamodbstore.NewSnapshotStore(tableName,
+		dynamodbstore.WithDynamoDB(api),
+	)
+	assert.Nil(t, err)
+
+	aggregateID := "abc"
+	_, err = store.LoadSnapshot(ctx, aggregateID)
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrSnapshotNotFound))
+
+	err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 2, Data: []byte("b")})
+	assert.Nil(t, err)
+
+	// older snapshots are ignored
+	err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 1, Data: []byte("a")})
+	assert.Nil(t, err)
+
+	snapshot, err := store.LoadSnapshot(ctx, aggregateID)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.Snapshot{Version: 2, Data: []byte("b")}, snapshot)
+}
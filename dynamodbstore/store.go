@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"reflect"
 	"sort"
 	"strconv"
 
@@ -45,6 +44,7 @@ type Store struct {
 	rangeKey      string
 	api           *dynamodb.DynamoDB
 	eventsPerItem int
+	streamTable   string
 	debug         bool
 	writer        io.Writer
 }
@@ -61,12 +61,12 @@ func (s *Store) checkIdempotent(ctx context.Context, aggregateID string, records
 		return err
 	}
 	if len(history) < len(records) {
-		return errors.New(awsConditionalCheckFailed)
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
 	}
 
 	recent := history[len(history)-len(records):]
-	if !reflect.DeepEqual(recent, eventsource.History(records)) {
-		return errors.New(awsConditionalCheckFailed)
+	if !recent.SameEvents(records) {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
 	}
 
 	return nil
@@ -83,13 +83,48 @@ func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventso
 		return err
 	}
 
+	return s.updateItem(ctx, aggregateID, input, records...)
+}
+
+// SaveVersion implements the eventsource.VersionedStore interface.  Versions are assumed to be
+// sequential so the records to be saved must begin at expectedVersion + 1.
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	input, err := makeUpdateItemInput(s.tableName, s.hashKey, s.rangeKey, s.eventsPerItem, aggregateID, records...)
+	if err != nil {
+		return err
+	}
+
+	if v := records[0].Version; v != expectedVersion+1 {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "expected aggregate, %v, to be at version %v; records begin at version %v", aggregateID, expectedVersion, v)
+	}
+
+	// when the expected version is stored in the same item, we can additionally verify it exists
+	if expectedVersion > 0 && selectPartition(expectedVersion, s.eventsPerItem) == selectPartition(records[0].Version, s.eventsPerItem) {
+		key := makeKey(expectedVersion)
+		nameRef := "#" + key
+		input.ExpressionAttributeNames[nameRef] = aws.String(key)
+		input.ConditionExpression = aws.String(*input.ConditionExpression + " AND attribute_exists(" + nameRef + ")")
+	}
+
+	return s.updateItem(ctx, aggregateID, input, records...)
+}
+
+func (s *Store) updateItem(ctx context.Context, aggregateID string, input *dynamodb.UpdateItemInput, records ...eventsource.Record) error {
+	if s.streamTable != "" {
+		return s.transact(ctx, pendingBatch{aggregateID: aggregateID, records: records, input: input})
+	}
+
 	if s.debug {
 		encoder := json.NewEncoder(s.writer)
 		encoder.SetIndent("", "  ")
 		encoder.Encode(input)
 	}
 
-	_, err = s.api.UpdateItem(input)
+	_, err := s.api.UpdateItem(input)
 	if err != nil {
 		if v, ok := err.(awserr.Error); ok {
 			if v.Code() == awsConditionalCheckFailed {
@@ -114,7 +149,6 @@ func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVer
 
 	history := make(eventsource.History, 0, toVersion)
 
-	var startKey map[string]*dynamodb.AttributeValue
 	for {
 		out, err := s.api.Query(input)
 		if err != nil {
@@ -145,16 +179,17 @@ func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVer
 				}
 
 				history = append(history, eventsource.Record{
-					Version: recordVersion,
-					Data:    av.B,
+					Version:  recordVersion,
+					Data:     av.B,
+					Metadata: metadataFromItem(item, recordVersion),
 				})
 			}
 		}
 
-		startKey = out.LastEvaluatedKey
-		if len(startKey) == 0 {
+		if len(out.LastEvaluatedKey) == 0 {
 			break
 		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
 	}
 
 	sort.Slice(history, func(i, j int) bool {
@@ -257,6 +292,14 @@ func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int,
 		fmt.Fprintf(updateExpr, "%v = %v", nameRef, valueRef)
 		input.ExpressionAttributeNames[nameRef] = aws.String(key)
 		input.ExpressionAttributeValues[valueRef] = &dynamodb.AttributeValue{B: record.Data}
+
+		// metadata, when present, is stored alongside the event as a map attribute
+		if metadata := metadataValue(record.Metadata); metadata != nil {
+			metadataKey := makeMetadataKey(record.Version)
+			fmt.Fprintf(updateExpr, ", #%v = :%v", metadataKey, metadataKey)
+			input.ExpressionAttributeNames["#"+metadataKey] = aws.String(metadataKey)
+			input.ExpressionAttributeValues[":"+metadataKey] = metadata
+		}
 	}
 
 	input.ConditionExpression = aws.String(condExpr.String())
@@ -266,7 +309,8 @@ func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int,
 }
 
 // makeQueryInput
-//  - partition - fetch up to this partition number; 0 to fetch all partitions
+//  - fromPartition - fetch from this partition number
+//  - toPartition - fetch up to this partition number; 0 to fetch all partitions
 func makeQueryInput(tableName, hashKey, rangeKey string, aggregateID string, fromPartition, toPartition int) (*dynamodb.QueryInput, error) {
 	input := &dynamodb.QueryInput{
 		TableName:      aws.String(tableName),
@@ -280,9 +324,14 @@ func makeQueryInput(tableName, hashKey, rangeKey string, aggregateID string, fro
 		},
 	}
 
-	if toPartition == 0 {
+	if toPartition == 0 && fromPartition == 0 {
 		input.KeyConditionExpression = aws.String("#key = :key")
 
+	} else if toPartition == 0 {
+		input.KeyConditionExpression = aws.String("#key = :key AND #partition >= :from")
+		input.ExpressionAttributeNames["#partition"] = aws.String(rangeKey)
+		input.ExpressionAttributeValues[":from"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(fromPartition))}
+
 	} else {
 		input.KeyConditionExpression = aws.String("#key = :key AND #partition >= :from AND #partition <= :to")
 		input.ExpressionAttributeNames["#partition"] = aws.String(rangeKey)
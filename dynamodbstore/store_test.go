@@ -257,3 +257,89 @@ func TestStore_LoadPartition(t *testing.T) {
 		assert.Equal(t, history[0:1], found)
 	})
 }
+
+func TestStore_SaveVersion(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	TempTable(t, api, func(tableName string) {
+		ctx := context.Background()
+		store, err := dynamodbstore.New(tableName,
+			dynamodbstore.WithDynamoDB(api),
+		)
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		initial := eventsource.Record{Version: 1, Data: []byte("a")}
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// saving the same record again is idempotent
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// a different record at the same version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		// a stale expected version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 3, Data: []byte("c")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 2)
+	})
+}
+
+func TestStore_SaveAll(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	TempTable(t, api, func(tableName string) {
+		ctx := context.Background()
+		store, err := dynamodbstore.New(tableName,
+			dynamodbstore.WithDynamoDB(api),
+		)
+		assert.Nil(t, err)
+
+		batches := []eventsource.Batch{
+			{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("a")}}},
+			{AggregateID: "def", Records: eventsource.History{{Version: 1, Data: []byte("b")}}},
+		}
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		// saving the same batches again is idempotent
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		// a conflict in one aggregate prevents the other from being saved
+		err = store.SaveAll(ctx,
+			eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 2, Data: []byte("c")}}},
+			eventsource.Batch{AggregateID: "def", Records: eventsource.History{{Version: 1, Data: []byte("d")}}},
+		)
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		found, err := store.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+	})
+}
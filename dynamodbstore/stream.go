@@ -0,0 +1,227 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/altairsix/eventsource"
+	apex "github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StreamHashKey is the hash key of the stream table; all entries share the same hash key so
+	// they can be queried in offset order
+	StreamHashKey = "stream"
+
+	// StreamRangeKey is the range key of the stream table; holds the offset of the entry
+	StreamRangeKey = "offset"
+
+	// StreamLastField holds the most recently assigned offset in the stream table's counter item
+	StreamLastField = "last"
+
+	// StreamAggregateIDField holds the aggregate id of the stream entry
+	StreamAggregateIDField = "aggregate_id"
+
+	// StreamVersionField holds the version of the stream entry
+	StreamVersionField = "version"
+
+	// StreamDataField holds the serialized event of the stream entry
+	StreamDataField = "data"
+
+	// StreamMetadataField holds the metadata of the stream entry, if any
+	StreamMetadataField = "metadata"
+
+	// streamID is the value of the hash key shared by all entries
+	streamID = "events"
+)
+
+func streamKey(offset uint64) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		StreamHashKey:  {S: aws.String(streamID)},
+		StreamRangeKey: {N: aws.String(strconv.FormatUint(offset, 10))},
+	}
+}
+
+// lastOffset returns the most recently assigned offset in the stream table; 0 if none
+func (s *Store) lastOffset(ctx context.Context) (uint64, error) {
+	out, err := s.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.streamTable),
+		ConsistentRead: aws.Bool(true),
+		Key:            streamKey(0),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to read stream offset")
+	}
+
+	av, ok := out.Item[StreamLastField]
+	if !ok || av.N == nil {
+		return 0, nil
+	}
+
+	last, err := strconv.ParseUint(*av.N, 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid stream offset")
+	}
+
+	return last, nil
+}
+
+// makeStreamItems returns the transact items that append the records to the stream table.  The
+// first item advances the counter, stored at offset 0, on condition that no one else has; it is
+// followed by one entry per record
+func makeStreamItems(tableName string, last uint64, pending ...pendingBatch) []*dynamodb.TransactWriteItem {
+	next := last
+	var entries []*dynamodb.TransactWriteItem
+	for _, batch := range pending {
+		for _, record := range batch.records {
+			next++
+			item := streamKey(next)
+			item[StreamAggregateIDField] = &dynamodb.AttributeValue{S: aws.String(batch.aggregateID)}
+			item[StreamVersionField] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(record.Version))}
+			item[StreamDataField] = &dynamodb.AttributeValue{B: record.Data}
+			if metadata := metadataValue(record.Metadata); metadata != nil {
+				item[StreamMetadataField] = metadata
+			}
+
+			entries = append(entries, &dynamodb.TransactWriteItem{
+				Put: &dynamodb.Put{
+					TableName: aws.String(tableName),
+					Item:      item,
+				},
+			})
+		}
+	}
+
+	condition := "#last = :last"
+	if last == 0 {
+		condition = "attribute_not_exists(#last) OR #last = :last"
+	}
+
+	counter := &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:           aws.String(tableName),
+			Key:                 streamKey(0),
+			ConditionExpression: aws.String(condition),
+			UpdateExpression:    aws.String("SET #last = :next"),
+			ExpressionAttributeNames: map[string]*string{
+				"#last": aws.String(StreamLastField),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":last": {N: aws.String(strconv.FormatUint(last, 10))},
+				":next": {N: aws.String(strconv.FormatUint(next, 10))},
+			},
+		},
+	}
+
+	return append([]*dynamodb.TransactWriteItem{counter}, entries...)
+}
+
+// Read implements the eventsource.StreamReader interface; requires the Store to have been created
+// WithStreamTable.  As with pgstore, offsets begin at 1 and records are returned in the order they
+// were saved
+func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	if s.streamTable == "" {
+		return nil, eventsource.NewError(nil, eventsource.ErrUnsupported, "dynamodbstore requires WithStreamTable to read the event stream")
+	}
+	if startingOffset == 0 {
+		startingOffset = 1
+	}
+
+	records := make([]eventsource.StreamRecord, 0, recordCount)
+	if recordCount <= 0 {
+		return records, nil
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.streamTable),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("#stream = :stream AND #offset >= :offset"),
+		ExpressionAttributeNames: map[string]*string{
+			"#stream": aws.String(StreamHashKey),
+			"#offset": aws.String(StreamRangeKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":stream": {S: aws.String(streamID)},
+			":offset": {N: aws.String(strconv.FormatUint(startingOffset, 10))},
+		},
+	}
+
+	for len(records) < recordCount {
+		input.Limit = aws.Int64(int64(recordCount - len(records)))
+
+		out, err := s.api.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "read failed; unable to query stream table")
+		}
+
+		for _, item := range out.Items {
+			record, err := streamRecordFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	return records, nil
+}
+
+// StreamChanges returns the entry added to a stream table, see WithStreamTable, by a dynamodb stream
+// record; the entry carries its stream offset.  Changes to the stream table's counter yield no
+// entries.  An error is returned if the record does not belong to a stream table
+func StreamChanges(record *apex.Record) ([]eventsource.StreamRecord, error) {
+	if record == nil || record.Dynamodb == nil {
+		return nil, nil
+	}
+
+	key, ok := record.Dynamodb.Keys[StreamRangeKey]
+	if !ok || key.N == nil {
+		return nil, errors.Errorf("stream record does not contain the stream table range key, %v", StreamRangeKey)
+	}
+	if *key.N == "0" || record.Dynamodb.NewImage == nil {
+		return nil, nil
+	}
+
+	item, err := streamRecordFromItem(record.Dynamodb.NewImage)
+	if err != nil {
+		return nil, err
+	}
+
+	return []eventsource.StreamRecord{item}, nil
+}
+
+func streamRecordFromItem(item map[string]*dynamodb.AttributeValue) (eventsource.StreamRecord, error) {
+	record := eventsource.StreamRecord{}
+
+	if av, ok := item[StreamRangeKey]; ok && av.N != nil {
+		offset, err := strconv.ParseUint(*av.N, 10, 64)
+		if err != nil {
+			return eventsource.StreamRecord{}, errors.Wrap(err, "invalid stream offset")
+		}
+		record.Offset = offset
+	}
+	if av, ok := item[StreamVersionField]; ok && av.N != nil {
+		version, err := strconv.Atoi(*av.N)
+		if err != nil {
+			return eventsource.StreamRecord{}, errors.Wrapf(err, "invalid version at stream offset %v", record.Offset)
+		}
+		record.Version = version
+	}
+	if av, ok := item[StreamAggregateIDField]; ok {
+		record.AggregateID = aws.StringValue(av.S)
+	}
+	if av, ok := item[StreamDataField]; ok {
+		record.Data = av.B
+	}
+	record.Metadata = metadataFromValue(item[StreamMetadataField])
+
+	return record, nil
+}
@@ -0,0 +1,45 @@
+package dynamodbstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeStreamItems(t *testing.T) {
+	items := makeStreamItems("stream", 2,
+		pendingBatch{aggregateID: "abc", records: eventsource.History{{Version: 1}, {Version: 2}}},
+		pendingBatch{aggregateID: "def", records: eventsource.History{{Version: 1}}},
+	)
+	assert.Len(t, items, 4)
+
+	// the counter is advanced on condition that it has not moved
+	counter := items[0].Update
+	assert.Equal(t, "#last = :last", *counter.ConditionExpression)
+	assert.Equal(t, "2", *counter.ExpressionAttributeValues[":last"].N)
+	assert.Equal(t, "5", *counter.ExpressionAttributeValues[":next"].N)
+
+	for i, item := range items[1:] {
+		record, err := streamRecordFromItem(item.Put.Item)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(i+3), record.Offset)
+	}
+
+	items = makeStreamItems("stream", 0, pendingBatch{aggregateID: "abc", records: eventsource.History{{Version: 1}}})
+	assert.Equal(t, "attribute_not_exists(#last) OR #last = :last", *items[0].Update.ConditionExpression)
+}
+
+func TestTransactItemLimit(t *testing.T) {
+	records := make(eventsource.History, 99)
+	for i := range records {
+		records[i].Version = i + 1
+	}
+
+	// the aggregate, 99 entries and the counter exceed the limit before dynamodb is called
+	s := &Store{streamTable: "stream"}
+	err := s.transact(context.Background(), pendingBatch{aggregateID: "abc", records: records})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "101 items")
+}
@@ -0,0 +1,81 @@
+package dynamodbstore_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_ImplementsStreamReader(t *testing.T) {
+	v, err := dynamodbstore.New("blah")
+	assert.Nil(t, err)
+
+	var reader eventsource.StreamReader = v
+	assert.NotNil(t, reader)
+
+	// reading requires a stream table
+	_, err = v.Read(context.Background(), 0, 10)
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+}
+
+func TestStore_SaveAndRead(t *testing.T) {
+	t.Parallel()
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	TempTable(t, api, func(tableName string) {
+		streamTable := tableName + "-stream"
+		_, err := api.CreateTable(dynamodbstore.MakeCreateStreamTableInput(streamTable, 50, 50))
+		assert.Nil(t, err)
+		defer api.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(streamTable)})
+
+		ctx := context.Background()
+		store, err := dynamodbstore.New(tableName,
+			dynamodbstore.WithDynamoDB(api),
+			dynamodbstore.WithStreamTable(streamTable),
+		)
+		assert.Nil(t, err)
+
+		metadata := eventsource.Metadata{eventsource.ActorKey: "joe"}
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a"), Metadata: metadata})
+		assert.Nil(t, err)
+		err = store.SaveVersion(ctx, "def", 0, eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.Nil(t, err)
+		err = store.SaveAll(ctx,
+			eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 2, Data: []byte("c")}}},
+			eventsource.Batch{AggregateID: "def", Records: eventsource.History{{Version: 2, Data: []byte("d")}}},
+		)
+		assert.Nil(t, err)
+
+		// idempotent saves do not add to the stream
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a"), Metadata: metadata})
+		assert.Nil(t, err)
+
+		records, err := store.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []eventsource.StreamRecord{
+			{Offset: 1, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a"), Metadata: metadata}},
+			{Offset: 2, AggregateID: "def", Record: eventsource.Record{Version: 1, Data: []byte("b")}},
+			{Offset: 3, AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: []byte("c")}},
+			{Offset: 4, AggregateID: "def", Record: eventsource.Record{Version: 2, Data: []byte("d")}},
+		}, records)
+
+		records, err = store.Read(ctx, 3, 1)
+		assert.Nil(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, uint64(3), records[0].Offset)
+	})
+}
@@ -0,0 +1,74 @@
+package dynamodbstore_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/compressstore"
+	"github.com/altairsix/eventsource/cryptostore"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStore_Wrappers runs the stores that wrap another store against dynamodb; the behaviour of
+// each wrapper is covered by its own package against an in-memory store
+func TestStore_Wrappers(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	TempTable(t, api, func(tableName string) {
+		inner, err := dynamodbstore.New(tableName, dynamodbstore.WithDynamoDB(api))
+		assert.Nil(t, err)
+
+		ctx := context.Background()
+
+		t.Run("cryptostore", func(t *testing.T) {
+			store := cryptostore.New(inner, cryptostore.NewMemoryKeyStore())
+
+			history := eventsource.History{
+				{Version: 1, Data: []byte("alice@example.com")},
+				{Version: 2, Data: []byte("bob@example.com")},
+			}
+			assert.Nil(t, store.Save(ctx, "crypto", history...))
+			assert.Nil(t, store.Save(ctx, "crypto", history...), "saving the same records again should remain idempotent")
+
+			err := store.SaveVersion(ctx, "crypto", 1, eventsource.Record{Version: 2, Data: []byte("eve@example.com")})
+			assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+			found, err := store.Load(ctx, "crypto", 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, history, found)
+
+			assert.Nil(t, store.Erase(ctx, "crypto"))
+			_, err = store.Load(ctx, "crypto", 0, 0)
+			assert.True(t, cryptostore.IsErased(err))
+		})
+
+		t.Run("compressstore", func(t *testing.T) {
+			// uncompressed, these records exceed the 400KB limit of the single item that holds them
+			data := bytes.Repeat([]byte(`{"type":"ItemAdded","sku":"ABC-123","quantity":1},`), 120)
+			var history eventsource.History
+			for i := 1; i <= 100; i++ {
+				history = append(history, eventsource.Record{Version: i, Data: data})
+			}
+			assert.NotNil(t, inner.Save(ctx, "compress", history...))
+
+			store := compressstore.New(inner, compressstore.WithCodec(compressstore.Zstd))
+			assert.Nil(t, store.Save(ctx, "compress", history...))
+
+			found, err := store.Load(ctx, "compress", 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, history, found)
+		})
+	})
+}
@@ -25,6 +25,17 @@ const (
 	// UnhandledEvent occurs when the Aggregate is unable to handle an event and returns
 	// a non-nill err
 	ErrUnhandledEvent = "UnhandledEvent"
+
+	// ConcurrencyConflict is returned when the records being saved conflict with records
+	// already in the Store e.g. the aggregate was modified after it was loaded
+	ErrConcurrencyConflict = "ConcurrencyConflict"
+
+	// SnapshotNotFound will be returned when attempting to load a snapshot for an aggregate
+	// that has not been snapshotted
+	ErrSnapshotNotFound = "SnapshotNotFound"
+
+	// Unsupported is returned when the Store does not support the requested operation
+	ErrUnsupported = "Unsupported"
 )
 
 // Error provides a standardized error interface for eventsource
@@ -105,3 +116,8 @@ func IsNotFound(err error) bool {
 
 	return false
 }
+
+// IsConcurrencyConflict returns true if the save failed because the aggregate was modified concurrently
+func IsConcurrencyConflict(err error) bool {
+	return ErrHasCode(err, ErrConcurrencyConflict)
+}
@@ -84,3 +84,10 @@ func TestErrHasCode(t *testing.T) {
 		})
 	}
 }
+
+func TestIsConcurrencyConflict(t *testing.T) {
+	err := eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "conflict")
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+	assert.False(t, eventsource.IsConcurrencyConflict(errors.New("blah")))
+	assert.False(t, eventsource.IsConcurrencyConflict(nil))
+}
@@ -0,0 +1,262 @@
+package filestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// frameHeaderSize is the size of the length and checksum that precede every payload
+	frameHeaderSize = 8
+
+	// maxPayloadSize guards against reading garbage lengths from a torn frame
+	maxPayloadSize = 1 << 30
+
+	// flagCommit marks the final entry of a Save; entries following the last commit are discarded
+	// during recovery so a Save is never half applied
+	flagCommit = 1
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// errTorn indicates a frame was incomplete or failed its checksum
+	errTorn = errors.New("torn frame")
+)
+
+// appendFrame appends the payload, preceded by its length and checksum, to buf
+func appendFrame(buf []byte, payload []byte) []byte {
+	var header [frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+
+	buf = append(buf, header[:]...)
+	return append(buf, payload...)
+}
+
+// readFrame reads the next frame from r; returns io.EOF at a clean end of input and errTorn if
+// the frame is incomplete or corrupt
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxPayloadSize {
+		return nil, errTorn
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errTorn
+	}
+
+	return payload, nil
+}
+
+// decodeFrame validates a complete frame read from a known position
+func decodeFrame(frame []byte) ([]byte, error) {
+	if len(frame) < frameHeaderSize {
+		return nil, errTorn
+	}
+
+	payload := frame[frameHeaderSize:]
+	if binary.BigEndian.Uint32(frame[0:4]) != uint32(len(payload)) ||
+		crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(frame[4:8]) {
+		return nil, errTorn
+	}
+
+	return payload, nil
+}
+
+// decoder reads the fields of a payload in order; the first error encountered sticks
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("malformed payload")
+	}
+}
+
+func (d *decoder) readByte() byte {
+	if d.err != nil || len(d.data) < 1 {
+		d.fail()
+		return 0
+	}
+	v := d.data[0]
+	d.data = d.data[1:]
+	return v
+}
+
+func (d *decoder) readUint64() uint64 {
+	if d.err != nil || len(d.data) < 8 {
+		d.fail()
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) readVarint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) readBytes() []byte {
+	if d.err != nil {
+		return nil
+	}
+	length, n := binary.Uvarint(d.data)
+	if n <= 0 || uint64(len(d.data)-n) < length {
+		d.fail()
+		return nil
+	}
+	v := d.data[n : n+int(length)]
+	d.data = d.data[n+int(length):]
+	if len(v) == 0 {
+		return nil
+	}
+	return append([]byte(nil), v...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendBytes(buf []byte, v []byte) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], uint64(len(v)))
+	buf = append(buf, b[:n]...)
+	return append(buf, v...)
+}
+
+// entry is a single record as written to a segment
+type entry struct {
+	Offset      uint64
+	AggregateID string
+	Record      eventsource.Record
+	Commit      bool
+}
+
+func (e entry) marshal() ([]byte, error) {
+	var metadata []byte
+	if len(e.Record.Metadata) > 0 {
+		v, err := json.Marshal(e.Record.Metadata)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to marshal metadata for version %v of aggregate, %v", e.Record.Version, e.AggregateID)
+		}
+		metadata = v
+	}
+
+	var flags byte
+	if e.Commit {
+		flags |= flagCommit
+	}
+
+	buf := make([]byte, 0, 32+len(e.AggregateID)+len(e.Record.Data)+len(metadata))
+	buf = append(buf, flags)
+	buf = appendUint64(buf, e.Offset)
+	buf = appendVarint(buf, int64(e.Record.Version))
+	buf = appendBytes(buf, []byte(e.AggregateID))
+	buf = appendBytes(buf, e.Record.Data)
+	buf = appendBytes(buf, metadata)
+	return buf, nil
+}
+
+func unmarshalEntry(payload []byte) (entry, error) {
+	d := &decoder{data: payload}
+	flags := d.readByte()
+	e := entry{
+		Offset:      d.readUint64(),
+		Commit:      flags&flagCommit != 0,
+		Record:      eventsource.Record{Version: int(d.readVarint())},
+		AggregateID: string(d.readBytes()),
+	}
+	e.Record.Data = d.readBytes()
+	metadata := d.readBytes()
+	if d.err != nil {
+		return entry{}, d.err
+	}
+
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &e.Record.Metadata); err != nil {
+			return entry{}, errors.Wrap(err, "unable to unmarshal metadata")
+		}
+	}
+
+	return e, nil
+}
+
+// indexEntry locates a single record within the segments
+type indexEntry struct {
+	Offset      uint64
+	Segment     uint64
+	Position    int64
+	Size        int64
+	Version     int
+	AggregateID string
+}
+
+func (i indexEntry) marshal() []byte {
+	buf := make([]byte, 0, 48+len(i.AggregateID))
+	buf = appendUint64(buf, i.Offset)
+	buf = appendUint64(buf, i.Segment)
+	buf = appendVarint(buf, i.Position)
+	buf = appendVarint(buf, i.Size)
+	buf = appendVarint(buf, int64(i.Version))
+	buf = appendBytes(buf, []byte(i.AggregateID))
+	return buf
+}
+
+func unmarshalIndexEntry(payload []byte) (indexEntry, error) {
+	d := &decoder{data: payload}
+	i := indexEntry{
+		Offset:   d.readUint64(),
+		Segment:  d.readUint64(),
+		Position: d.readVarint(),
+		Size:     d.readVarint(),
+		Version:  int(d.readVarint()),
+	}
+	i.AggregateID = string(d.readBytes())
+	return i, d.err
+}
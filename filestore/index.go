@@ -0,0 +1,113 @@
+package filestore
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// indexName is the name of the index file within the store directory
+	indexName = "index"
+)
+
+// loadIndex reads the entries of the index file.  The index is only a hint to speed up startup so
+// a missing file or a torn tail simply yields fewer entries
+func loadIndex(path string) ([]indexEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open index, %v", path)
+	}
+	defer file.Close()
+
+	var entries []indexEntry
+	r := bufio.NewReaderSize(file, 64*1024)
+	for {
+		payload, err := readFrame(r)
+		if err == io.EOF || err == errTorn {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read index, %v", path)
+		}
+
+		e, err := unmarshalIndexEntry(payload)
+		if err != nil {
+			return entries, nil
+		}
+		entries = append(entries, e)
+	}
+}
+
+// compactIndex atomically replaces the index with the specified entries ordered by aggregate and
+// version, discarding stale and duplicate entries accumulated since the last compaction.  Returns
+// the index opened for appending
+func compactIndex(dir string, entries []indexEntry) (*os.File, int64, error) {
+	sorted := append([]indexEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].AggregateID != sorted[j].AggregateID {
+			return sorted[i].AggregateID < sorted[j].AggregateID
+		}
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	path := filepath.Join(dir, indexName)
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "unable to create index, %v", tmp)
+	}
+
+	w := bufio.NewWriterSize(file, 64*1024)
+	var size int64
+	for _, e := range sorted {
+		frame := appendFrame(nil, e.marshal())
+		if _, err := w.Write(frame); err != nil {
+			file.Close()
+			return nil, 0, errors.Wrapf(err, "unable to write index, %v", tmp)
+		}
+		size += int64(len(frame))
+	}
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return nil, 0, errors.Wrapf(err, "unable to write index, %v", tmp)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, 0, errors.Wrapf(err, "unable to sync index, %v", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		file.Close()
+		return nil, 0, errors.Wrapf(err, "unable to replace index, %v", path)
+	}
+	if err := syncDir(dir); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, size, nil
+}
+
+// syncDir flushes the directory entry so newly created or renamed files survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open directory, %v", dir)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return errors.Wrapf(err, "unable to sync directory, %v", dir)
+	}
+
+	return nil
+}
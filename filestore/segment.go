@@ -0,0 +1,152 @@
+package filestore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// segmentExt is the extension of segment files; the name of each segment is the offset of
+	// its first record
+	segmentExt = ".log"
+)
+
+// segment is a single file of the log
+type segment struct {
+	base uint64
+	path string
+	file *os.File
+	size int64
+}
+
+func segmentName(base uint64) string {
+	return fmt.Sprintf("%020d%v", base, segmentExt)
+}
+
+// openSegments opens every segment within dir ordered by base offset
+func openSegments(dir string) ([]*segment, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list segments in %v", dir)
+	}
+
+	var segments []*segment
+	for _, path := range paths {
+		base, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		seg, err := openSegment(path, base)
+		if err != nil {
+			closeSegments(segments)
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].base < segments[j].base
+	})
+
+	return segments, nil
+}
+
+func openSegment(path string, base uint64) (*segment, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open segment, %v", path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrapf(err, "unable to stat segment, %v", path)
+	}
+
+	return &segment{
+		base: base,
+		path: path,
+		file: file,
+		size: info.Size(),
+	}, nil
+}
+
+func closeSegments(segments []*segment) {
+	for _, seg := range segments {
+		seg.file.Close()
+	}
+}
+
+// scan calls fn for each committed entry in the segment and returns the size of the segment up to
+// the end of the last committed entry.  Entries following the last commit, and anything after a
+// torn frame, are not delivered
+func (s *segment) scan(fn func(e entry, position, size int64) error) (int64, error) {
+	type pending struct {
+		entry    entry
+		position int64
+		size     int64
+	}
+
+	r := bufio.NewReaderSize(io.NewSectionReader(s.file, 0, s.size), 64*1024)
+
+	var (
+		batch     []pending
+		position  int64
+		committed int64
+	)
+	for {
+		payload, err := readFrame(r)
+		if err == io.EOF || err == errTorn {
+			return committed, nil
+		}
+		if err != nil {
+			return committed, errors.Wrapf(err, "unable to read segment, %v", s.path)
+		}
+
+		e, err := unmarshalEntry(payload)
+		if err != nil {
+			// the checksum matched so this is not a torn write
+			return committed, errors.Wrapf(err, "corrupt entry at position %v of segment, %v", position, s.path)
+		}
+
+		size := int64(frameHeaderSize + len(payload))
+		batch = append(batch, pending{entry: e, position: position, size: size})
+		position += size
+
+		if !e.Commit {
+			continue
+		}
+
+		for _, p := range batch {
+			if err := fn(p.entry, p.position, p.size); err != nil {
+				return committed, err
+			}
+		}
+		batch = batch[:0]
+		committed = position
+	}
+}
+
+// read returns the entry at the specified position
+func (s *segment) read(position, size int64) (entry, error) {
+	frame := make([]byte, size)
+	if _, err := s.file.ReadAt(frame, position); err != nil {
+		return entry{}, errors.Wrapf(err, "unable to read position %v of segment, %v", position, s.path)
+	}
+
+	payload, err := decodeFrame(frame)
+	if err != nil {
+		return entry{}, errors.Wrapf(err, "corrupt entry at position %v of segment, %v", position, s.path)
+	}
+
+	return unmarshalEntry(payload)
+}
@@ -0,0 +1,569 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultSegmentSize is the size at which a new segment is started by default
+	DefaultSegmentSize = 64 * 1024 * 1024
+)
+
+// SyncPolicy determines when writes are flushed to stable storage
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes each Save before returning; nothing acknowledged is lost on a crash
+	SyncAlways SyncPolicy = iota
+
+	// SyncInterval flushes periodically in the background; a crash may lose the Saves made since
+	// the last flush
+	SyncInterval
+
+	// SyncNever leaves flushing to the operating system; a crash may lose any recent Saves
+	SyncNever
+)
+
+// Option provides functional configuration for a *Store
+type Option func(*Store)
+
+// WithSegmentSize specifies the size at which a new segment file is started.  A single Save is
+// never split across segments so segments may grow slightly beyond this size
+func WithSegmentSize(n int64) Option {
+	return func(s *Store) {
+		if n > 0 {
+			s.segmentSize = n
+		}
+	}
+}
+
+// WithSyncPolicy specifies when writes are flushed to stable storage; defaults to SyncAlways
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(s *Store) {
+		s.policy = policy
+	}
+}
+
+// WithSyncInterval flushes writes in the background at the specified interval; shorthand for
+// WithSyncPolicy(SyncInterval) with the interval given
+func WithSyncInterval(d time.Duration) Option {
+	return func(s *Store) {
+		s.policy = SyncInterval
+		s.syncInterval = d
+	}
+}
+
+// WithDebug will generate additional logging useful for debugging
+func WithDebug(w io.Writer) Option {
+	return func(s *Store) {
+		s.writer = w
+		s.debug = true
+	}
+}
+
+// location identifies where a record lives within the segments
+type location struct {
+	segment  *segment
+	position int64
+	size     int64
+	version  int
+}
+
+// Store provides an eventsource.Store and eventsource.StreamReader backed by append-only segment
+// files in a local directory.  Each record is assigned a global offset, beginning at 1, in the
+// order it was saved.  An index of the records held by each aggregate is kept alongside the
+// segments; it is rebuilt from the segments as needed and compacted each time the store is opened.
+//
+// A directory must only be opened by a single Store at a time
+type Store struct {
+	dir          string
+	segmentSize  int64
+	policy       SyncPolicy
+	syncInterval time.Duration
+	writer       io.Writer
+	debug        bool
+
+	mux        sync.RWMutex
+	segments   []*segment
+	active     *segment
+	index      *os.File
+	indexSize  int64
+	locations  []location
+	aggregates map[string][]uint64
+	closed     bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (s *Store) logf(format string, args ...interface{}) {
+	if !s.debug {
+		return
+	}
+
+	now := time.Now().Format(time.StampMilli)
+	io.WriteString(s.writer, now)
+	io.WriteString(s.writer, " ")
+
+	fmt.Fprintf(s.writer, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		io.WriteString(s.writer, "\n")
+	}
+}
+
+// Save the provided serialized records to the store.  Either all of the records are saved or none
+// are
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	items := append(eventsource.History(nil), records...)
+	sort.Sort(items)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return errors.New("save failed; store is closed")
+	}
+
+	if s.maxVersion(aggregateID) >= items[0].Version {
+		return s.isIdempotent(aggregateID, items...)
+	}
+
+	return s.append(aggregateID, items)
+}
+
+// SaveVersion saves the provided records only if the aggregate is currently at expectedVersion; implements
+// eventsource.VersionedStore
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	items := append(eventsource.History(nil), records...)
+	sort.Sort(items)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return errors.New("save failed; store is closed")
+	}
+
+	if s.maxVersion(aggregateID) != expectedVersion || items[0].Version <= expectedVersion {
+		return s.isIdempotent(aggregateID, items...)
+	}
+
+	return s.append(aggregateID, items)
+}
+
+func (s *Store) maxVersion(aggregateID string) int {
+	offsets := s.aggregates[aggregateID]
+	if len(offsets) == 0 {
+		return 0
+	}
+	return s.locations[offsets[len(offsets)-1]-1].version
+}
+
+func (s *Store) isIdempotent(aggregateID string, records ...eventsource.Record) error {
+	fromVersion := records[0].Version
+	toVersion := records[len(records)-1].Version
+	loaded, err := s.load(aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return errors.Wrapf(err, "unable to retrieve version %v-%v for aggregate, %v", fromVersion, toVersion, aggregateID)
+	}
+
+	if !eventsource.History(records).SameEvents(loaded) {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
+	}
+
+	return nil
+}
+
+// append writes the records, sorted by version, to the active segment followed by their index
+// entries.  If either write fails, both files are truncated back to their previous size
+func (s *Store) append(aggregateID string, records eventsource.History) error {
+	for i := 1; i < len(records); i++ {
+		if records[i].Version == records[i-1].Version {
+			return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; duplicate version %v for aggregate, %v", records[i].Version, aggregateID)
+		}
+	}
+
+	if s.active.size >= s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	seg := s.active
+	next := uint64(len(s.locations)) + 1
+
+	var (
+		data      []byte
+		index     []byte
+		locations = make([]location, 0, len(records))
+	)
+	for i, record := range records {
+		payload, err := entry{
+			Offset:      next + uint64(i),
+			AggregateID: aggregateID,
+			Record:      record,
+			Commit:      i == len(records)-1,
+		}.marshal()
+		if err != nil {
+			return err
+		}
+
+		position := seg.size + int64(len(data))
+		data = appendFrame(data, payload)
+		size := seg.size + int64(len(data)) - position
+
+		index = appendFrame(index, indexEntry{
+			Offset:      next + uint64(i),
+			Segment:     seg.base,
+			Position:    position,
+			Size:        size,
+			Version:     record.Version,
+			AggregateID: aggregateID,
+		}.marshal())
+		locations = append(locations, location{segment: seg, position: position, size: size, version: record.Version})
+	}
+
+	if _, err := seg.file.WriteAt(data, seg.size); err != nil {
+		seg.file.Truncate(seg.size)
+		return errors.Wrapf(err, "unable to write to segment, %v", seg.path)
+	}
+	if s.policy == SyncAlways {
+		if err := seg.file.Sync(); err != nil {
+			seg.file.Truncate(seg.size)
+			return errors.Wrapf(err, "unable to sync segment, %v", seg.path)
+		}
+	}
+
+	// the index need not be synced; entries lost on a crash are rebuilt from the segments
+	if _, err := s.index.WriteAt(index, s.indexSize); err != nil {
+		s.index.Truncate(s.indexSize)
+		seg.file.Truncate(seg.size)
+		return errors.Wrap(err, "unable to write to index")
+	}
+
+	seg.size += int64(len(data))
+	s.indexSize += int64(len(index))
+	for i, loc := range locations {
+		s.locations = append(s.locations, loc)
+		s.aggregates[aggregateID] = append(s.aggregates[aggregateID], next+uint64(i))
+	}
+
+	return nil
+}
+
+// rotate starts a new segment whose base is the next offset to be written
+func (s *Store) rotate() error {
+	if s.policy != SyncNever {
+		if err := s.active.file.Sync(); err != nil {
+			return errors.Wrapf(err, "unable to sync segment, %v", s.active.path)
+		}
+	}
+
+	base := uint64(len(s.locations)) + 1
+	seg, err := openSegment(filepath.Join(s.dir, segmentName(base)), base)
+	if err != nil {
+		return err
+	}
+	if s.policy != SyncNever {
+		if err := syncDir(s.dir); err != nil {
+			seg.file.Close()
+			return err
+		}
+	}
+
+	s.logf("Started segment, %v", seg.path)
+	s.segments = append(s.segments, seg)
+	s.active = seg
+
+	return nil
+}
+
+// Load the history of events up to the version specified; when version is
+// 0, all events will be loaded
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.closed {
+		return nil, errors.New("load failed; store is closed")
+	}
+
+	return s.load(aggregateID, fromVersion, toVersion)
+}
+
+func (s *Store) load(aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	history := eventsource.History{}
+	for _, offset := range s.aggregates[aggregateID] {
+		loc := s.locations[offset-1]
+		if loc.version < fromVersion || (toVersion > 0 && loc.version > toVersion) {
+			continue
+		}
+
+		e, err := loc.segment.read(loc.position, loc.size)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load failed; unable to read version %v of aggregate, %v", loc.version, aggregateID)
+		}
+		history = append(history, e.Record)
+	}
+
+	return history, nil
+}
+
+// Read implements the eventsource.StreamReader interface; offsets begin at 1
+func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.closed {
+		return nil, errors.New("read failed; store is closed")
+	}
+
+	if startingOffset == 0 {
+		startingOffset = 1
+	}
+
+	records := make([]eventsource.StreamRecord, 0)
+	for offset := startingOffset; offset <= uint64(len(s.locations)) && len(records) < recordCount; offset++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		loc := s.locations[offset-1]
+		e, err := loc.segment.read(loc.position, loc.size)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read failed; unable to read offset %v", offset)
+		}
+		records = append(records, eventsource.StreamRecord{
+			Record:      e.Record,
+			Offset:      e.Offset,
+			AggregateID: e.AggregateID,
+		})
+	}
+
+	return records, nil
+}
+
+// Close flushes any outstanding writes and releases the files held by the store
+func (s *Store) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mux.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+
+	var err error
+	if s.policy != SyncNever {
+		err = s.active.file.Sync()
+	}
+	for _, seg := range s.segments {
+		if v := seg.file.Close(); v != nil && err == nil {
+			err = v
+		}
+	}
+	if v := s.index.Close(); v != nil && err == nil {
+		err = v
+	}
+
+	return err
+}
+
+// syncLoop flushes the active segment periodically for SyncInterval
+func (s *Store) syncLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mux.RLock()
+			seg := s.active
+			s.mux.RUnlock()
+
+			if err := seg.file.Sync(); err != nil {
+				s.logf("Unable to sync segment, %v: %v", seg.path, err)
+			}
+		}
+	}
+}
+
+// recover rebuilds the in-memory index from the index file and segments.  Any torn or
+// uncommitted writes at the end of the final segment are truncated
+func (s *Store) recover() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory, %v", s.dir)
+	}
+
+	segments, err := openSegments(s.dir)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		seg, err := openSegment(filepath.Join(s.dir, segmentName(1)), 1)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, seg)
+	}
+	s.segments = segments
+	s.active = segments[len(segments)-1]
+
+	bySegment := map[uint64]*segment{}
+	for _, seg := range segments {
+		bySegment[seg.base] = seg
+	}
+
+	// the index is trusted for every segment but the last; the last is always scanned as it is
+	// the only one that may hold a torn write
+	indexed, err := loadIndex(filepath.Join(s.dir, indexName))
+	if err != nil {
+		return err
+	}
+	sort.Slice(indexed, func(i, j int) bool {
+		return indexed[i].Offset < indexed[j].Offset
+	})
+
+	var entries []indexEntry
+	for _, e := range indexed {
+		if e.Offset != uint64(len(entries))+1 {
+			if e.Offset <= uint64(len(entries)) {
+				continue // duplicate
+			}
+			break // gap; the remainder is rebuilt from the segments
+		}
+		seg, ok := bySegment[e.Segment]
+		if !ok || seg == s.active || e.Position+e.Size > seg.size {
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	// rebuild everything after the indexed entries from the segments
+	first := 0
+	for i, seg := range segments {
+		if seg.base <= uint64(len(entries))+1 {
+			first = i
+		}
+	}
+	for _, seg := range segments[first:] {
+		committed, err := seg.scan(func(e entry, position, size int64) error {
+			if e.Offset <= uint64(len(entries)) {
+				return nil
+			}
+			if e.Offset != uint64(len(entries))+1 {
+				return errors.Errorf("expected offset %v but found %v in segment, %v", len(entries)+1, e.Offset, seg.path)
+			}
+			entries = append(entries, indexEntry{
+				Offset:      e.Offset,
+				Segment:     seg.base,
+				Position:    position,
+				Size:        size,
+				Version:     e.Record.Version,
+				AggregateID: e.AggregateID,
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if committed == seg.size {
+			continue
+		}
+		if seg != s.active {
+			return errors.Errorf("segment, %v, is corrupt at position %v", seg.path, committed)
+		}
+
+		s.logf("Truncating torn write at position %v of segment, %v", committed, seg.path)
+		if err := seg.file.Truncate(committed); err != nil {
+			return errors.Wrapf(err, "unable to truncate segment, %v", seg.path)
+		}
+		if err := seg.file.Sync(); err != nil {
+			return errors.Wrapf(err, "unable to sync segment, %v", seg.path)
+		}
+		seg.size = committed
+	}
+
+	s.locations = make([]location, 0, len(entries))
+	s.aggregates = map[string][]uint64{}
+	for _, e := range entries {
+		s.locations = append(s.locations, location{
+			segment:  bySegment[e.Segment],
+			position: e.Position,
+			size:     e.Size,
+			version:  e.Version,
+		})
+		s.aggregates[e.AggregateID] = append(s.aggregates[e.AggregateID], e.Offset)
+	}
+
+	index, size, err := compactIndex(s.dir, entries)
+	if err != nil {
+		return err
+	}
+	s.index = index
+	s.indexSize = size
+
+	s.logf("Recovered %v record(s) from %v segment(s) in %v", len(entries), len(segments), s.dir)
+	return nil
+}
+
+// New opens the store held in dir, creating the directory if needed, and recovers from any
+// previous crash
+func New(dir string, opts ...Option) (*Store, error) {
+	s := &Store{
+		dir:          dir,
+		segmentSize:  DefaultSegmentSize,
+		policy:       SyncAlways,
+		syncInterval: time.Second,
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.recover(); err != nil {
+		closeSegments(s.segments)
+		return nil, err
+	}
+
+	if s.policy == SyncInterval {
+		s.wg.Add(1)
+		go s.syncLoop()
+	}
+
+	return s, nil
+}
@@ -0,0 +1,335 @@
+package filestore_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func WithDir(t *testing.T, callback func(dir string)) {
+	dir, err := ioutil.TempDir("", "filestore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	callback(dir)
+}
+
+func segments(t *testing.T, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.Nil(t, err)
+	return names
+}
+
+func TestStore_Implements(t *testing.T) {
+	WithDir(t, func(dir string) {
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		var store eventsource.Store = s
+		assert.NotNil(t, store)
+
+		var versioned eventsource.VersionedStore = s
+		assert.NotNil(t, versioned)
+
+		var reader eventsource.StreamReader = s
+		assert.NotNil(t, reader)
+	})
+}
+
+func TestStore_SaveAndFetch(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{Version: 1, Data: []byte("a")},
+			{Version: 2, Data: []byte("b")},
+			{Version: 3, Data: []byte("c")},
+		}
+		err = s.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := s.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+
+		found, err = s.Load(ctx, aggregateID, 2, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, history[1:2], found)
+
+		found, err = s.Load(ctx, "missing", 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, eventsource.History{}, found)
+	})
+}
+
+func TestStore_SaveIdempotent(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{Version: 1, Data: []byte("a")},
+			{Version: 2, Data: []byte("b")},
+		}
+		err = s.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		err = s.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err, "repeated save of the same records should succeed")
+
+		err = s.Save(ctx, aggregateID, eventsource.Record{Version: 2, Data: []byte("x")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		err = s.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, Data: []byte("x")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		err = s.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 3, Data: []byte("c")})
+		assert.Nil(t, err)
+
+		found, err := s.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 3)
+	})
+}
+
+func TestStore_Read(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		for i := 1; i <= 5; i++ {
+			err := s.Save(ctx, strconv.Itoa(i%2), eventsource.Record{Version: (i + 1) / 2, Data: []byte(strconv.Itoa(i))})
+			assert.Nil(t, err)
+		}
+
+		records, err := s.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 5)
+		for i, record := range records {
+			assert.Equal(t, uint64(i+1), record.Offset)
+			assert.Equal(t, strconv.Itoa(i+1), string(record.Data))
+			assert.Equal(t, strconv.Itoa((i+1)%2), record.AggregateID)
+		}
+
+		records, err = s.Read(ctx, 4, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, uint64(4), records[0].Offset)
+
+		records, err = s.Read(ctx, 2, 2)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+
+		records, err = s.Read(ctx, 6, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 0)
+	})
+}
+
+func TestStore_Rotate(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir, filestore.WithSegmentSize(128))
+		assert.Nil(t, err)
+
+		for i := 1; i <= 20; i++ {
+			err := s.Save(ctx, "abc", eventsource.Record{Version: i, Data: []byte("some event data " + strconv.Itoa(i))})
+			assert.Nil(t, err)
+		}
+		assert.True(t, len(segments(t, dir)) > 1)
+		assert.Nil(t, s.Close())
+
+		s, err = filestore.New(dir, filestore.WithSegmentSize(128))
+		assert.Nil(t, err)
+		defer s.Close()
+
+		found, err := s.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 20)
+
+		records, err := s.Read(ctx, 1, 100)
+		assert.Nil(t, err)
+		assert.Len(t, records, 20)
+
+		err = s.Save(ctx, "abc", eventsource.Record{Version: 21})
+		assert.Nil(t, err)
+
+		records, err = s.Read(ctx, 21, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(21), records[0].Offset)
+	})
+}
+
+func TestStore_RecoverTornWrite(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+
+		err = s.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+
+		names := segments(t, dir)
+		assert.Len(t, names, 1)
+		info, err := os.Stat(names[0])
+		assert.Nil(t, err)
+		size := info.Size()
+
+		// simulate a crash part way through a write
+		f, err := os.OpenFile(names[0], os.O_WRONLY|os.O_APPEND, 0644)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte{0, 0, 0, 40, 1, 2, 3, 4, 5})
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+
+		s, err = filestore.New(dir)
+		assert.Nil(t, err)
+
+		info, err = os.Stat(names[0])
+		assert.Nil(t, err)
+		assert.Equal(t, size, info.Size(), "torn write should have been truncated")
+
+		err = s.Save(ctx, "abc", eventsource.Record{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+
+		s, err = filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		records, err := s.Read(ctx, 1, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "b", string(records[1].Data))
+	})
+}
+
+func TestStore_RecoverUncommittedBatch(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir)
+		assert.Nil(t, err)
+
+		err = s.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+
+		names := segments(t, dir)
+
+		// write a second batch elsewhere and graft all but its final byte onto the original segment
+		WithDir(t, func(other string) {
+			s, err := filestore.New(other)
+			assert.Nil(t, err)
+			err = s.Save(ctx, "abc",
+				eventsource.Record{Version: 1, Data: []byte("a")},
+				eventsource.Record{Version: 2, Data: []byte("b")},
+				eventsource.Record{Version: 3, Data: []byte("c")},
+			)
+			assert.Nil(t, err)
+			assert.Nil(t, s.Close())
+
+			data, err := ioutil.ReadFile(segments(t, other)[0])
+			assert.Nil(t, err)
+			assert.Nil(t, ioutil.WriteFile(names[0], data[:len(data)-1], 0644))
+		})
+
+		s, err = filestore.New(dir)
+		assert.Nil(t, err)
+		defer s.Close()
+
+		found, err := s.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 0, "partially written batch should be discarded entirely")
+
+		info, err := os.Stat(names[0])
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), info.Size())
+	})
+}
+
+func TestStore_RebuildIndex(t *testing.T) {
+	WithDir(t, func(dir string) {
+		ctx := context.Background()
+		s, err := filestore.New(dir, filestore.WithSegmentSize(64))
+		assert.Nil(t, err)
+
+		for i := 1; i <= 10; i++ {
+			err := s.Save(ctx, strconv.Itoa(i%3), eventsource.Record{Version: (i + 2) / 3, Data: []byte(strconv.Itoa(i))})
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, s.Close())
+
+		assert.Nil(t, os.Remove(filepath.Join(dir, "index")))
+
+		s, err = filestore.New(dir, filestore.WithSegmentSize(64))
+		assert.Nil(t, err)
+		defer s.Close()
+
+		_, err = os.Stat(filepath.Join(dir, "index"))
+		assert.Nil(t, err, "index should have been rebuilt")
+
+		found, err := s.Load(ctx, "1", 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 4)
+		assert.Equal(t, "10", string(found[3].Data))
+
+		records, err := s.Read(ctx, 1, 100)
+		assert.Nil(t, err)
+		assert.Len(t, records, 10)
+	})
+}
+
+func TestStore_SyncPolicies(t *testing.T) {
+	policies := map[string]filestore.Option{
+		"always":   filestore.WithSyncPolicy(filestore.SyncAlways),
+		"interval": filestore.WithSyncInterval(10 * time.Millisecond),
+		"never":    filestore.WithSyncPolicy(filestore.SyncNever),
+	}
+
+	for label, opt := range policies {
+		t.Run(label, func(t *testing.T) {
+			WithDir(t, func(dir string) {
+				ctx := context.Background()
+				s, err := filestore.New(dir, opt, filestore.WithSegmentSize(64))
+				assert.Nil(t, err)
+
+				for i := 1; i <= 5; i++ {
+					err := s.Save(ctx, "abc", eventsource.Record{Version: i, Data: []byte("data")})
+					assert.Nil(t, err)
+				}
+				time.Sleep(25 * time.Millisecond)
+				assert.Nil(t, s.Close())
+
+				err = s.Save(ctx, "abc", eventsource.Record{Version: 6})
+				assert.NotNil(t, err, "closed store should reject saves")
+
+				s, err = filestore.New(dir, opt)
+				assert.Nil(t, err)
+				defer s.Close()
+
+				found, err := s.Load(ctx, "abc", 0, 0)
+				assert.Nil(t, err)
+				assert.Len(t, found, 5)
+			})
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package sqltx holds the transaction handling shared by the database/sql backed stores
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// DB is the subset of *sql.DB and *sql.Tx used by the stores; it matches the DB interface each
+// store exports
+type DB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// txBeginner is implemented by *sql.DB
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// WithTx invokes fn within a transaction.  If db is unable to begin a transaction, e.g. because it
+// is already a *sql.Tx, fn is invoked with db directly
+func WithTx(ctx context.Context, db DB, fn func(db DB) error) error {
+	beginner, ok := db.(txBeginner)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to begin transaction")
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit transaction")
+	}
+
+	return nil
+}
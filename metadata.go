@@ -0,0 +1,92 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const (
+	// CorrelationIDKey is the metadata key that identifies the request or workflow the event belongs to
+	CorrelationIDKey = "correlation_id"
+
+	// CausationIDKey is the metadata key that identifies the message that caused the event
+	CausationIDKey = "causation_id"
+
+	// ActorKey is the metadata key that identifies who caused the event
+	ActorKey = "actor"
+)
+
+// Metadata contains information about the circumstances under which an event was recorded e.g.
+// correlation id, causation id, actor, or any custom header.  Metadata is stored alongside, but
+// separate from, the serialized event
+type Metadata map[string]string
+
+// CorrelationID returns the correlation id or "" if not set
+func (m Metadata) CorrelationID() string {
+	return m[CorrelationIDKey]
+}
+
+// CausationID returns the causation id or "" if not set
+func (m Metadata) CausationID() string {
+	return m[CausationIDKey]
+}
+
+// Actor returns the actor or "" if not set
+func (m Metadata) Actor() string {
+	return m[ActorKey]
+}
+
+type metadataKey struct{}
+
+// ContextWithMetadata returns a copy of ctx that carries the metadata provided merged with any
+// metadata already carried by ctx.  The Repository attaches this metadata to every event saved
+// with the returned context
+func ContextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	merged := Metadata{}
+	for k, v := range MetadataFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range md {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// MetadataFromContext returns a copy of the metadata carried by ctx; returns nil if ctx carries
+// no metadata
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	if len(md) == 0 {
+		return nil
+	}
+
+	dupe := make(Metadata, len(md))
+	for k, v := range md {
+		dupe[k] = v
+	}
+
+	return dupe
+}
+
+// MarshalMetadata encodes the metadata as json for stores that hold it in a single column; empty
+// metadata is encoded as nil so that it may be stored as NULL
+func MarshalMetadata(metadata Metadata) ([]byte, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(metadata)
+}
+
+// UnmarshalMetadata decodes metadata encoded by MarshalMetadata
+func UnmarshalMetadata(data []byte) (Metadata, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	metadata := Metadata{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,109 @@
+package eventsource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, eventsource.MetadataFromContext(ctx))
+
+	ctx = eventsource.ContextWithMetadata(ctx, eventsource.Metadata{
+		eventsource.CorrelationIDKey: "abc",
+		eventsource.ActorKey:         "joe",
+	})
+	ctx = eventsource.ContextWithMetadata(ctx, eventsource.Metadata{
+		eventsource.CausationIDKey: "def",
+		eventsource.ActorKey:       "jane",
+	})
+
+	md := eventsource.MetadataFromContext(ctx)
+	assert.Equal(t, "abc", md.CorrelationID())
+	assert.Equal(t, "def", md.CausationID())
+	assert.Equal(t, "jane", md.Actor())
+
+	// returned metadata is a copy
+	md["tenant"] = "acme"
+	assert.Len(t, eventsource.MetadataFromContext(ctx), 3)
+}
+
+func TestApplyWithMetadata(t *testing.T) {
+	store := eventsource.New(&Entity{}).Store()
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{})),
+	)
+
+	metadata := eventsource.Metadata{eventsource.CorrelationIDKey: "abc"}
+	ctx := eventsource.ContextWithMetadata(context.Background(), metadata)
+
+	_, err := repo.Apply(ctx, &CreateEntity{CommandModel: eventsource.CommandModel{ID: "123"}})
+	assert.Nil(t, err)
+
+	history, err := store.Load(ctx, "123", 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, metadata, history[0].Metadata)
+
+	records, err := store.(eventsource.StreamReader).Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, metadata, records[0].Metadata)
+}
+
+// saveRecorder records the history passed to each call to Save
+type saveRecorder struct {
+	eventsource.Store
+	history eventsource.History
+}
+
+func (s *saveRecorder) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	s.history = append(s.history, records...)
+	return s.Store.Save(ctx, aggregateID, records...)
+}
+
+func TestSaveWithMetadata(t *testing.T) {
+	store := &saveRecorder{Store: eventsource.New(&Entity{}).Store()}
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{}, EntityNameSet{})),
+	)
+
+	metadata := eventsource.Metadata{eventsource.CorrelationIDKey: "abc"}
+	ctx := eventsource.ContextWithMetadata(context.Background(), metadata)
+
+	err := repo.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: "123", Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: "123", Version: 2}, Name: "blah"},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, store.history, 2)
+
+	// records do not share their metadata
+	store.history[0].Metadata[eventsource.ActorKey] = "joe"
+	assert.Equal(t, metadata, store.history[1].Metadata)
+}
+
+func TestMarshalMetadata(t *testing.T) {
+	data, err := eventsource.MarshalMetadata(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+
+	metadata, err := eventsource.UnmarshalMetadata(data)
+	assert.Nil(t, err)
+	assert.Nil(t, metadata)
+
+	data, err = eventsource.MarshalMetadata(eventsource.Metadata{eventsource.ActorKey: "joe"})
+	assert.Nil(t, err)
+
+	metadata, err = eventsource.UnmarshalMetadata(data)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.Metadata{eventsource.ActorKey: "joe"}, metadata)
+
+	_, err = eventsource.UnmarshalMetadata([]byte("junk"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,40 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+)
+
+// ApplyFunc applies a command to the aggregate provided and returns the resulting events
+type ApplyFunc func(ctx context.Context, aggregate Aggregate, command Command) ([]Event, error)
+
+// CommandMiddleware wraps the application of a command to an aggregate.  A middleware sees the
+// command and the loaded aggregate before calling next and the emitted events after.  It may
+// replace any of them or short-circuit the command by returning without calling next
+type CommandMiddleware func(next ApplyFunc) ApplyFunc
+
+// WithMiddleware adds middleware around the application of commands by Apply.  Middleware is
+// invoked in the order provided i.e. the first middleware is the outermost
+func WithMiddleware(middleware ...CommandMiddleware) Option {
+	return func(r *Repository) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
+// applyCommand is the innermost ApplyFunc; it dispatches the command to the aggregate's CommandHandler
+func applyCommand(ctx context.Context, aggregate Aggregate, command Command) ([]Event, error) {
+	h, ok := aggregate.(CommandHandler)
+	if !ok {
+		return nil, fmt.Errorf("Aggregate, %v, does not implement CommandHandler", aggregate)
+	}
+
+	return h.Apply(ctx, command)
+}
+
+// chain wraps fn with the middleware provided such that middleware[0] is the outermost
+func chain(fn ApplyFunc, middleware ...CommandMiddleware) ApplyFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		fn = middleware[i](fn)
+	}
+	return fn
+}
@@ -0,0 +1,85 @@
+package eventsource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	ctx := context.Background()
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "123"}}
+	newRepository := func(middleware ...eventsource.CommandMiddleware) *eventsource.Repository {
+		return eventsource.New(&Entity{},
+			eventsource.WithSerializer(
+				eventsource.NewJSONSerializer(
+					EntityCreated{},
+					EntityNameSet{},
+				),
+			),
+			eventsource.WithMiddleware(middleware...),
+		)
+	}
+
+	t.Run("invoked in order", func(t *testing.T) {
+		calls := []string{}
+		record := func(name string) eventsource.CommandMiddleware {
+			return func(next eventsource.ApplyFunc) eventsource.ApplyFunc {
+				return func(ctx context.Context, aggregate eventsource.Aggregate, command eventsource.Command) ([]eventsource.Event, error) {
+					calls = append(calls, name+":before")
+					events, err := next(ctx, aggregate, command)
+					calls = append(calls, name+":after")
+					return events, err
+				}
+			}
+		}
+		repo := newRepository(record("a"), record("b"))
+
+		_, err := repo.Apply(ctx, cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a:before", "b:before", "b:after", "a:after"}, calls)
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		rejected := errors.New("rejected")
+		repo := newRepository(func(next eventsource.ApplyFunc) eventsource.ApplyFunc {
+			return func(ctx context.Context, aggregate eventsource.Aggregate, command eventsource.Command) ([]eventsource.Event, error) {
+				return nil, rejected
+			}
+		})
+
+		_, err := repo.Apply(ctx, cmd)
+		assert.Equal(t, rejected, err)
+
+		_, err = repo.Load(ctx, cmd.AggregateID())
+		assert.True(t, eventsource.IsNotFound(err))
+	})
+
+	t.Run("change events", func(t *testing.T) {
+		repo := newRepository(func(next eventsource.ApplyFunc) eventsource.ApplyFunc {
+			return func(ctx context.Context, aggregate eventsource.Aggregate, command eventsource.Command) ([]eventsource.Event, error) {
+				events, err := next(ctx, aggregate, command)
+				if err != nil {
+					return nil, err
+				}
+
+				version := aggregate.(*Entity).Version + len(events) + 1
+				return append(events, &EntityNameSet{
+					Model: eventsource.Model{ID: command.AggregateID(), Version: version},
+					Name:  "middleware",
+				}), nil
+			}
+		})
+
+		version, err := repo.Apply(ctx, cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, version)
+
+		v, err := repo.Load(ctx, cmd.AggregateID())
+		assert.Nil(t, err)
+		assert.Equal(t, "middleware", v.(*Entity).Name)
+	})
+}
@@ -0,0 +1,74 @@
+package mysqlstore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	saveCheckpointSQL = `INSERT INTO ${TABLE} (name, position) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE position = VALUES(position)`
+	loadCheckpointSQL = `SELECT position FROM ${TABLE} WHERE name = ?`
+)
+
+// CheckpointStore records the progress of subscriptions in mysql; implements
+// subscription.CheckpointStore
+type CheckpointStore struct {
+	tableName string
+	accessor  Accessor
+}
+
+// SaveCheckpoint saves the offset of the next record to be read by the named subscription
+func (c *CheckpointStore) SaveCheckpoint(ctx context.Context, name string, offset uint64) error {
+	db, err := c.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save checkpoint failed; unable to connect to db")
+	}
+	defer c.accessor.Close(db)
+
+	stmt, err := db.PrepareContext(ctx, expand(saveCheckpointSQL, c.tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to prepare statement")
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(name, offset)
+	if err != nil {
+		return errors.Wrapf(err, "unable to save checkpoint for subscription, %v", name)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint returns the offset saved for the named subscription; 0 if none has been saved
+func (c *CheckpointStore) LoadCheckpoint(ctx context.Context, name string) (uint64, error) {
+	db, err := c.accessor.Open(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "load checkpoint failed; unable to connect to db")
+	}
+	defer c.accessor.Close(db)
+
+	rows, err := db.Query(expand(loadCheckpointSQL, c.tableName), name)
+	if err != nil {
+		return 0, errors.Wrap(err, "load checkpoint failed; unable to query rows")
+	}
+	defer rows.Close()
+
+	var offset uint64
+	if rows.Next() {
+		if err := rows.Scan(&offset); err != nil {
+			return 0, errors.Wrap(err, "load checkpoint failed; unable to parse row")
+		}
+	}
+
+	return offset, nil
+}
+
+// NewCheckpointStore returns a new mysql backed checkpoint store
+func NewCheckpointStore(tableName string, accessor Accessor) (*CheckpointStore, error) {
+	return &CheckpointStore{
+		tableName: tableName,
+		accessor:  accessor,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package mysqlstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource/mysqlstore"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointStore_ImplementsCheckpointStore(t *testing.T) {
+	v, err := mysqlstore.NewCheckpointStore("blah", nil)
+	assert.Nil(t, err)
+
+	var store subscription.CheckpointStore = v
+	assert.NotNil(t, store)
+}
+
+func TestCheckpointStore_SaveAndLoad(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := mysqlstore.NewCheckpointStore(tableName+"_checkpoints", Accessor{db: db})
+		assert.Nil(t, err)
+
+		offset, err := store.LoadCheckpoint(ctx, "sample")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0), offset)
+
+		err = store.SaveCheckpoint(ctx, "sample", 10)
+		assert.Nil(t, err)
+
+		err = store.SaveCheckpoint(ctx, "sample", 20)
+		assert.Nil(t, err)
+
+		offset, err = store.LoadCheckpoint(ctx, "sample")
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(20), offset)
+	})
+}
@@ -13,7 +13,8 @@ const (
 		id           INT PRIMARY KEY AUTO_INCREMENT,
 		aggregate_id VARCHAR(255),
 		data         VARBINARY(4096),
-		version      INT
+		version      INT,
+		metadata     BLOB
 	) ENGINE=InnoDB AUTO_INCREMENT=0 DEFAULT CHARSET=utf8;
 `
 
@@ -27,11 +28,53 @@ const (
 		AND table_name='${TABLE}' AND index_name='idx_${TABLE}';
 `
 
+	// CheckMetadataSQL provides sql to query db to determine whether the metadata column exists
+	CheckMetadataSQL = `
+	SELECT
+		COUNT(*) ColumnIsThere
+	FROM
+		INFORMATION_SCHEMA.COLUMNS
+	WHERE table_schema=DATABASE()
+		AND table_name='${TABLE}' AND column_name='metadata';
+`
+
+	// AddMetadataSQL provides sql to add the metadata column to tables created before it existed
+	AddMetadataSQL = `
+	ALTER TABLE ${TABLE} ADD COLUMN metadata BLOB;
+`
+
 	// CreateIndexSQL provides sql to create the index
 	CreateIndexSQL = `
 	CREATE UNIQUE INDEX idx_${TABLE}
 	ON ${TABLE} (aggregate_id, version);
 `
+
+	// CreateSnapshotSQL provides sql to create the snapshot table
+	CreateSnapshotSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		aggregate_id VARCHAR(255) PRIMARY KEY,
+		version      INT NOT NULL,
+		data         LONGBLOB
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`
+	// CreateOutboxSQL provides sql to create the outbox table
+	CreateOutboxSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		id           INT PRIMARY KEY AUTO_INCREMENT,
+		aggregate_id VARCHAR(255) NOT NULL,
+		version      INT NOT NULL,
+		data         VARBINARY(4096),
+		metadata     BLOB,
+		delivered_at DATETIME NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`
+	// CreateCheckpointSQL provides sql to create the subscription checkpoint table
+	CreateCheckpointSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		name     VARCHAR(255) PRIMARY KEY,
+		position BIGINT UNSIGNED NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`
 )
 
 func expand(template, tableName string) string {
@@ -45,6 +88,10 @@ func CreateIfNotExists(db DB, tableName string) error {
 		return errors.Wrap(err, "unable to create table")
 	}
 
+	if err := addMetadataIfNotExists(db, tableName); err != nil {
+		return err
+	}
+
 	row, err := db.Query(expand(CheckIndexSQL, tableName))
 	if err != nil {
 		return errors.Wrap(err, "query failed to determine if index exists")
@@ -68,3 +115,58 @@ func CreateIfNotExists(db DB, tableName string) error {
 
 	return err
 }
+
+func addMetadataIfNotExists(db DB, tableName string) error {
+	row, err := db.Query(expand(CheckMetadataSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "query failed to determine if metadata column exists")
+	}
+	defer row.Close()
+
+	row.Next()
+	exists := 0
+	if err := row.Scan(&exists); err != nil {
+		return errors.Wrap(err, "unable to read response for whether metadata column exists")
+	}
+
+	if exists > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(expand(AddMetadataSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to add metadata column")
+	}
+
+	return nil
+}
+
+// CreateSnapshotIfNotExists creates the specified snapshot table in the db if it does not already exist
+func CreateSnapshotIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateSnapshotSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create snapshot table")
+	}
+
+	return nil
+}
+
+// CreateOutboxIfNotExists creates the specified outbox table in the db if it does not already exist
+func CreateOutboxIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateOutboxSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create outbox table")
+	}
+
+	return nil
+}
+
+// CreateCheckpointIfNotExists creates the specified checkpoint table in the db if it does not already exist
+func CreateCheckpointIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateCheckpointSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create checkpoint table")
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package mysqlstore
+
+import (
+	"context"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/internal/sqltx"
+	"github.com/pkg/errors"
+)
+
+const (
+	insertOutboxSQL  = `INSERT INTO ${TABLE} (aggregate_id, version, data, metadata) VALUES (?, ?, ?, ?)`
+	pendingOutboxSQL = `SELECT id, aggregate_id, version, data, metadata FROM ${TABLE} WHERE delivered_at IS NULL ORDER BY id LIMIT ?`
+	markOutboxSQL    = `UPDATE ${TABLE} SET delivered_at = NOW() WHERE id = ?`
+)
+
+func insertOutbox(ctx context.Context, db DB, tableName, aggregateID string, records ...eventsource.Record) error {
+	stmt, err := db.PrepareContext(ctx, expand(insertOutboxSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to prepare outbox statement")
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		metadata, err := eventsource.MarshalMetadata(record.Metadata)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal metadata for version %v of aggregate, %v", record.Version, aggregateID)
+		}
+
+		_, err = stmt.Exec(aggregateID, record.Version, record.Data, metadata)
+		if err != nil {
+			return errors.Wrapf(err, "unable to insert version %v for aggregate, %v, into outbox", record.Version, aggregateID)
+		}
+	}
+
+	return nil
+}
+
+// Outbox provides access to the records written to an outbox table by a Store configured
+// WithOutbox.  Outbox implements outbox.Source
+type Outbox struct {
+	tableName string
+	accessor  Accessor
+}
+
+// Pending returns up to limit records that have not yet been marked as delivered, in the order
+// they were saved.  The Offset of each record identifies its row within the outbox
+func (o *Outbox) Pending(ctx context.Context, limit int) ([]eventsource.StreamRecord, error) {
+	db, err := o.accessor.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "pending failed; unable to connect to db")
+	}
+	defer o.accessor.Close(db)
+
+	rows, err := db.Query(expand(pendingOutboxSQL, o.tableName), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "pending failed; unable to read records from outbox")
+	}
+	defer rows.Close()
+
+	records := make([]eventsource.StreamRecord, 0, limit)
+	for rows.Next() {
+		record := eventsource.StreamRecord{}
+		var metadata []byte
+		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Version, &record.Data, &metadata); err != nil {
+			return nil, errors.Wrap(err, "failed to scan outbox record from db")
+		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrap(err, "failed to parse metadata of outbox record")
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "pending failed; unable to read records from outbox")
+	}
+
+	return records, nil
+}
+
+// MarkDelivered marks the outbox records with the specified offsets as delivered
+func (o *Outbox) MarkDelivered(ctx context.Context, offsets ...uint64) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	db, err := o.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "mark delivered failed; unable to connect to db")
+	}
+	defer o.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		stmt, err := tx.PrepareContext(ctx, expand(markOutboxSQL, o.tableName))
+		if err != nil {
+			return errors.Wrap(err, "unable to prepare statement")
+		}
+		defer stmt.Close()
+
+		for _, offset := range offsets {
+			if _, err := stmt.Exec(offset); err != nil {
+				return errors.Wrapf(err, "unable to mark outbox record, %v, as delivered", offset)
+			}
+		}
+
+		return nil
+	})
+}
+
+// NewOutbox returns a new Outbox that reads from the specified outbox table
+func NewOutbox(tableName string, accessor Accessor) (*Outbox, error) {
+	return &Outbox{
+		tableName: tableName,
+		accessor:  accessor,
+	}, nil
+}
@@ -5,18 +5,22 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
-	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/internal/sqltx"
+	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 )
 
 const (
-	insertSQL = `INSERT INTO ${TABLE} (aggregate_id, data, version) VALUES (?, ?, ?)`
-	selectSQL = `SELECT data, version FROM ${TABLE} WHERE aggregate_id = ? AND version >= ? AND version <= ? ORDER BY version ASC`
-	readSQL   = `SELECT id, aggregate_id, data, version FROM ${TABLE} WHERE id >= ? ORDER BY ID LIMIT ?`
+	// mysqlDuplicateEntry is the mysql error number for a duplicate key
+	mysqlDuplicateEntry = 1062
+
+	insertSQL = `INSERT INTO ${TABLE} (aggregate_id, data, version, metadata) VALUES (?, ?, ?, ?)`
+	selectSQL = `SELECT data, version, metadata FROM ${TABLE} WHERE aggregate_id = ? AND version >= ? AND version <= ? ORDER BY version ASC`
+	readSQL   = `SELECT id, aggregate_id, data, version, metadata FROM ${TABLE} WHERE id >= ? ORDER BY ID LIMIT ?`
 )
 
 // DB provides a smaller surface area for the db calls used; Exec is only used by the create function
@@ -42,12 +46,43 @@ type Accessor interface {
 type Store struct {
 	tableName string
 	accessor  Accessor
+	outbox    string
+}
+
+// Option provides functional configuration for a *Store
+type Option func(*Store)
+
+// WithOutbox causes each saved record to also be written to the specified outbox table within
+// the same transaction as the event itself.  See Outbox for reading the records back
+func WithOutbox(tableName string) Option {
+	return func(s *Store) {
+		s.outbox = tableName
+	}
 }
 
 func (s *Store) expand(statement string) string {
 	return strings.Replace(statement, "${TABLE}", s.tableName, -1)
 }
 
+func (s *Store) maxVersion(ctx context.Context, db DB, aggregateID string) (int, error) {
+	row, err := db.Query(expand("SELECT MAX(version) FROM ${TABLE} WHERE aggregate_id = ?", s.tableName), aggregateID)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to query database")
+	}
+	defer row.Close()
+
+	maxVersion := 0
+	if row.Next() {
+		v := sql.NullInt64{}
+		if err := row.Scan(&v); err != nil {
+			return 0, errors.Wrap(err, "unable to read version info from database")
+		}
+		maxVersion = int(v.Int64)
+	}
+
+	return maxVersion, nil
+}
+
 // Save the provided serialized records to the store
 func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
 	if len(records) == 0 {
@@ -60,20 +95,126 @@ func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventso
 	}
 	defer s.accessor.Close(db)
 
-	stmt, err := db.PrepareContext(ctx, s.expand(insertSQL))
+	items := append(eventsource.History(nil), records...)
+	sort.Sort(items)
+
+	// the version check runs in the same transaction as the insert so a concurrent writer is
+	// caught by the unique index rather than silently interleaving
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion >= items[0].Version {
+			return s.isIdempotent(ctx, tx, aggregateID, items...)
+		}
+
+		return s.insertTx(ctx, tx, aggregateID, items...)
+	})
+}
+
+// SaveVersion saves the provided records only if the aggregate is currently at expectedVersion; implements
+// eventsource.VersionedStore
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion != expectedVersion {
+			return s.isIdempotent(ctx, tx, aggregateID, records...)
+		}
+
+		return s.insertTx(ctx, tx, aggregateID, records...)
+	})
+}
+
+// SaveAll saves the records of several aggregates within a single transaction; implements
+// eventsource.BatchStore
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		for _, batch := range batches {
+			if len(batch.Records) == 0 {
+				continue
+			}
+
+			maxVersion, err := s.maxVersion(ctx, tx, batch.AggregateID)
+			if err != nil {
+				return errors.Wrap(err, "save failed; unable to connect to db")
+			}
+
+			items := append(eventsource.History(nil), batch.Records...)
+			sort.Sort(items)
+
+			if maxVersion >= items[0].Version {
+				if err := s.isIdempotent(ctx, tx, batch.AggregateID, items...); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.insertTx(ctx, tx, batch.AggregateID, items...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// insertTx inserts the records and, if configured, the corresponding outbox records.  An error
+// with code ErrConcurrencyConflict is returned if a version already exists
+func (s *Store) insertTx(ctx context.Context, tx DB, aggregateID string, records ...eventsource.Record) error {
+	stmt, err := tx.PrepareContext(ctx, s.expand(insertSQL))
 	if err != nil {
 		return errors.Wrap(err, "unable to prepare statement")
 	}
 	defer stmt.Close()
 
 	for _, record := range records {
-		_, err = stmt.Exec(aggregateID, record.Data, record.Version)
+		metadata, err := eventsource.MarshalMetadata(record.Metadata)
 		if err != nil {
-			return s.isIdempotent(ctx, db, aggregateID, records...)
+			return errors.Wrapf(err, "unable to marshal metadata for version %v of aggregate, %v", record.Version, aggregateID)
+		}
+
+		_, err = stmt.Exec(aggregateID, record.Data, record.Version, metadata)
+		if err != nil {
+			if isVersionConflict(err) {
+				return eventsource.NewError(err, eventsource.ErrConcurrencyConflict, "unable to save records; version %v of aggregate, %v, already exists", record.Version, aggregateID)
+			}
+			return errors.Wrapf(err, "unable to insert version %v for aggregate, %v", record.Version, aggregateID)
 		}
 	}
 
-	return nil
+	if s.outbox == "" {
+		return nil
+	}
+	return insertOutbox(ctx, tx, s.outbox, aggregateID, records...)
+}
+
+// isVersionConflict returns true if err is a duplicate key error i.e. another writer saved the
+// same version first
+func isVersionConflict(err error) bool {
+	v, ok := errors.Cause(err).(*mysql.MySQLError)
+	return ok && v.Number == mysqlDuplicateEntry
 }
 
 func (s *Store) isIdempotent(ctx context.Context, db DB, aggregateID string, records ...eventsource.Record) error {
@@ -87,8 +228,8 @@ func (s *Store) isIdempotent(ctx context.Context, db DB, aggregateID string, rec
 		return fmt.Errorf("unable to retrieve version %v-%v for aggregate, %v", fromVersion, toVersion, aggregateID)
 	}
 
-	if !reflect.DeepEqual(segments, loaded) {
-		return fmt.Errorf("unable to save records; conflicting records detected for aggregate, %v", aggregateID)
+	if !segments.SameEvents(loaded) {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
 	}
 
 	return nil
@@ -115,15 +256,23 @@ func (s *Store) doLoad(ctx context.Context, db DB, aggregateID string, initialVe
 	if err != nil {
 		return nil, errors.Wrap(err, "load failed; unable to query rows")
 	}
+	defer rows.Close()
 
 	history := eventsource.History{}
 	for rows.Next() {
 		record := eventsource.Record{}
-		if err := rows.Scan(&record.Data, &record.Version); err != nil {
+		var metadata []byte
+		if err := rows.Scan(&record.Data, &record.Version, &metadata); err != nil {
 			return nil, errors.Wrap(err, "load failed; unable to parse row")
 		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrap(err, "load failed; unable to parse metadata")
+		}
 		history = append(history, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to read rows")
+	}
 
 	return history, nil
 }
@@ -144,21 +293,32 @@ func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int
 
 	for rows.Next() {
 		record := eventsource.StreamRecord{}
-		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Data, &record.Version); err != nil {
+		var metadata []byte
+		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Data, &record.Version, &metadata); err != nil {
 			return nil, errors.Wrapf(err, "failed to scan stream record from db")
 		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse metadata of stream record")
+		}
 		records = append(records, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "read failed; unable to read records from db")
+	}
 
 	return records, nil
 }
 
 // New returns a new postgres backed eventsource.Store
-func New(tableName string, accessor Accessor) (*Store, error) {
+func New(tableName string, accessor Accessor, opts ...Option) (*Store, error) {
 	store := &Store{
 		tableName: tableName,
 		accessor:  accessor,
 	}
 
+	for _, opt := range opts {
+		opt(store)
+	}
+
 	return store, nil
 }
@@ -0,0 +1,16 @@
+package mysqlstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVersionConflict_Inline(t *testing.T) {
+	assert.True(t, isVersionConflict(&mysql.MySQLError{Number: mysqlDuplicateEntry}))
+	assert.False(t, isVersionConflict(&mysql.MySQLError{Number: 1406})) // data too long
+	assert.False(t, isVersionConflict(&mysql.MySQLError{Number: 1213})) // deadlock
+	assert.False(t, isVersionConflict(errors.New("boom")))
+}
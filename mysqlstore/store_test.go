@@ -2,7 +2,10 @@ package mysqlstore_test
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/altairsix/eventsource"
 	"github.com/altairsix/eventsource/mysqlstore"
@@ -218,3 +221,156 @@ func TestStore_LoadPartition(t *testing.T) {
 		assert.Equal(t, history[0:1], found)
 	})
 }
+
+func TestStore_ImplementsVersionedStore(t *testing.T) {
+	v, err := mysqlstore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.VersionedStore = v
+	assert.NotNil(t, store)
+}
+
+func TestStore_SaveVersion(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := mysqlstore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		initial := eventsource.Record{Version: 1, Data: []byte("a")}
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// saving the same record again is idempotent
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// a different record at the same version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		// a stale expected version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 3, Data: []byte("c")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 2)
+	})
+}
+
+func TestStore_SaveMetadata(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := mysqlstore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		metadata := eventsource.Metadata{eventsource.CorrelationIDKey: "123", "tenant": "acme"}
+		history := eventsource.History{
+			{Version: 1, Data: []byte("a"), Metadata: metadata},
+			{Version: 2, Data: []byte("b")},
+		}
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+
+		records, err := store.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, metadata, records[0].Metadata)
+		assert.Nil(t, records[1].Metadata)
+
+		// a retry of the same save is idempotent even if its metadata differs
+		retry := eventsource.History{
+			{Version: 1, Data: []byte("a"), Metadata: eventsource.Metadata{eventsource.CorrelationIDKey: "456"}},
+			{Version: 2, Data: []byte("b")},
+		}
+		assert.Nil(t, store.Save(ctx, aggregateID, retry...))
+	})
+}
+
+func TestStore_ImplementsBatchStore(t *testing.T) {
+	v, err := mysqlstore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.BatchStore = v
+	assert.NotNil(t, store)
+}
+
+func TestStore_SaveAll(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := mysqlstore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		batches := []eventsource.Batch{
+			{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("a")}}},
+			{AggregateID: "def", Records: eventsource.History{{Version: 1, Data: []byte("b")}, {Version: 2, Data: []byte("c")}}},
+		}
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		// saving the same batches again is idempotent
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		for _, batch := range batches {
+			found, err := store.Load(ctx, batch.AggregateID, 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, batch.Records, found)
+		}
+
+		err = store.SaveAll(ctx, eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("x")}}})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+	})
+}
+
+func TestStore_SaveVersionIsAtomic(t *testing.T) {
+	// the save must manage its own transaction so this test cannot run inside WithRollback
+	db, err := sql.Open("mysql", dsn)
+	if !assert.Nil(t, err, "unable to open connection") {
+		return
+	}
+	defer db.Close()
+
+	tableName := "atomic_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if !assert.Nil(t, mysqlstore.CreateIfNotExists(db, tableName)) {
+		return
+	}
+	defer db.Exec("DROP TABLE " + tableName)
+
+	ctx := context.Background()
+	store, err := mysqlstore.New(tableName, Accessor{db: db})
+	assert.Nil(t, err)
+
+	// concurrent writers at the same expected version; exactly one may succeed
+	const writers = 4
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			errs <- store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: []byte(strconv.Itoa(i))})
+		}(i)
+	}
+
+	succeeded := 0
+	for i := 0; i < writers; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, eventsource.IsConcurrencyConflict(err), "expected concurrency conflict; got %v", err)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	found, err := store.Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+}
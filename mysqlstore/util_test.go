@@ -53,6 +53,18 @@ func WithRollback(t *testing.T, fn func(db DB, tableName string)) {
 		t.Errorf("unable to create table, %v", err)
 		return
 	}
+	if err := mysqlstore.CreateSnapshotIfNotExists(db, tableName+"_snapshots"); err != nil {
+		t.Errorf("unable to create snapshot table, %v", err)
+		return
+	}
+	if err := mysqlstore.CreateOutboxIfNotExists(db, tableName+"_outbox"); err != nil {
+		t.Errorf("unable to create outbox table, %v", err)
+		return
+	}
+	if err := mysqlstore.CreateCheckpointIfNotExists(db, tableName+"_checkpoints"); err != nil {
+		t.Errorf("unable to create checkpoint table, %v", err)
+		return
+	}
 	defer db.Close()
 
 	tx, err := db.Begin()
@@ -0,0 +1,178 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var errRepositoryClosed = errors.New("repository closed")
+
+// Observer receives each event saved by Repository.Apply along with the version of the aggregate
+// once the event has been applied.  The context provided carries the Metadata of the command and
+// may be read via MetadataFromContext
+type Observer interface {
+	Observe(ctx context.Context, version int, event Event) error
+}
+
+// ObserverFunc provides a func alternative to Observer
+type ObserverFunc func(ctx context.Context, version int, event Event) error
+
+// Observe implements the Observer interface
+func (fn ObserverFunc) Observe(ctx context.Context, version int, event Event) error {
+	return fn(ctx, version, event)
+}
+
+// ObserverOption provides functional configuration for an observer registered via WithObserver
+type ObserverOption func(*observer)
+
+// Async delivers events to the observer from a separate goroutine rather than from within Apply.
+// Up to queueSize events are buffered; once the queue is full, Apply blocks until there is room or
+// its context is done
+func Async(queueSize int) ObserverOption {
+	return func(o *observer) {
+		if queueSize < 0 {
+			queueSize = 0
+		}
+		o.queue = make(chan observation, queueSize)
+	}
+}
+
+// OnError specifies a callback to be invoked when the observer returns an error or the event could
+// not be delivered.  By default errors are written to the debug log
+func OnError(fn func(ctx context.Context, event Event, err error)) ObserverOption {
+	return func(o *observer) {
+		o.onError = fn
+	}
+}
+
+// WithObserver registers an observer that will receive each event saved by Apply.  By default,
+// events are delivered synchronously, after the events have been saved; errors returned by the
+// observer do not fail the command
+func WithObserver(o Observer, opts ...ObserverOption) Option {
+	return func(r *Repository) {
+		item := &observer{observer: o}
+		for _, opt := range opts {
+			opt(item)
+		}
+		r.observers = append(r.observers, item)
+	}
+}
+
+type observation struct {
+	ctx     context.Context
+	version int
+	event   Event
+}
+
+type observer struct {
+	observer Observer
+	onError  func(ctx context.Context, event Event, err error)
+	queue    chan observation // nil for synchronous observers
+	done     chan struct{}
+}
+
+// detachedContext retains the values of the parent context but not its deadline or cancellation
+// so that events queued for async observers may outlive the call to Apply
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// startObservers launches the goroutines that service the async observers
+func (r *Repository) startObservers() {
+	for _, o := range r.observers {
+		if o.queue == nil {
+			continue
+		}
+
+		o.done = make(chan struct{})
+		go func(o *observer) {
+			defer close(o.done)
+			for item := range o.queue {
+				r.observe(o, item)
+			}
+		}(o)
+	}
+}
+
+func (r *Repository) observe(o *observer, item observation) {
+	if err := o.observer.Observe(item.ctx, item.version, item.event); err != nil {
+		r.observerError(o, item.ctx, item.event, err)
+	}
+}
+
+func (r *Repository) observerError(o *observer, ctx context.Context, event Event, err error) {
+	if o.onError != nil {
+		o.onError(ctx, event, err)
+		return
+	}
+	r.logf("Observer failed on version %v of aggregate id, %v: %v", event.EventVersion(), event.AggregateID(), err)
+}
+
+// notify publishes the events to each of the registered observers.  Synchronous observers are
+// called without holding observerMux so that they may call Close
+func (r *Repository) notify(ctx context.Context, events ...Event) {
+	if len(r.observers) == 0 {
+		return
+	}
+
+	for _, event := range events {
+		item := observation{ctx: ctx, version: event.EventVersion(), event: event}
+
+		for _, o := range r.observers {
+			if o.queue == nil {
+				r.observe(o, item)
+				continue
+			}
+
+			r.enqueue(o, item)
+		}
+	}
+}
+
+// enqueue passes the observation to an async observer unless the repository has been closed
+func (r *Repository) enqueue(o *observer, item observation) {
+	r.observerMux.RLock()
+	defer r.observerMux.RUnlock()
+
+	if r.closed {
+		r.observerError(o, item.ctx, item.event, errRepositoryClosed)
+		return
+	}
+
+	select {
+	case o.queue <- observation{ctx: detachedContext{Context: item.ctx}, version: item.version, event: item.event}:
+	case <-item.ctx.Done():
+		r.observerError(o, item.ctx, item.event, item.ctx.Err())
+	}
+}
+
+// Close stops the delivery of new events to async observers and blocks until the events already
+// queued have been delivered.  Close should be called once the Repository is no longer in use.
+// Synchronous observers may call Close; async observers must not as Close waits for them to finish
+func (r *Repository) Close() error {
+	r.observerMux.Lock()
+	if r.closed {
+		r.observerMux.Unlock()
+		return nil
+	}
+	r.closed = true
+	for _, o := range r.observers {
+		if o.queue != nil {
+			close(o.queue)
+		}
+	}
+	r.observerMux.Unlock()
+
+	for _, o := range r.observers {
+		if o.done != nil {
+			<-o.done
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package eventsource_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithObserver(t *testing.T) {
+	var (
+		versions []int
+		actors   []string
+		failures []error
+	)
+
+	observer := eventsource.ObserverFunc(func(ctx context.Context, version int, event eventsource.Event) error {
+		versions = append(versions, version)
+		actors = append(actors, eventsource.MetadataFromContext(ctx).Actor())
+		return errors.New("boom")
+	})
+	onError := func(ctx context.Context, event eventsource.Event, err error) {
+		failures = append(failures, err)
+	}
+
+	repo := eventsource.New(&Entity{},
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{})),
+		eventsource.WithObserver(observer, eventsource.OnError(onError)),
+	)
+	defer repo.Close()
+
+	ctx := eventsource.ContextWithMetadata(context.Background(), eventsource.Metadata{eventsource.ActorKey: "joe"})
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "abc"}}
+
+	// observer errors do not fail the command
+	_, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+	_, err = repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []int{1, 2}, versions)
+	assert.Equal(t, []string{"joe", "joe"}, actors)
+	assert.Len(t, failures, 2)
+}
+
+func TestWithObserverAsync(t *testing.T) {
+	var (
+		mux      sync.Mutex
+		versions []int
+	)
+
+	release := make(chan struct{})
+	observer := eventsource.ObserverFunc(func(ctx context.Context, version int, event eventsource.Event) error {
+		<-release
+		mux.Lock()
+		defer mux.Unlock()
+		versions = append(versions, version)
+		return nil
+	})
+
+	repo := eventsource.New(&Entity{},
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{})),
+		eventsource.WithObserver(observer, eventsource.Async(10)),
+	)
+
+	// Apply does not wait for the observer
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "abc"}}
+	for i := 0; i < 3; i++ {
+		_, err := repo.Apply(context.Background(), cmd)
+		assert.Nil(t, err)
+	}
+
+	// Close drains the queue
+	close(release)
+	assert.Nil(t, repo.Close())
+	assert.Equal(t, []int{1, 2, 3}, versions)
+
+	// events applied after Close are not delivered
+	_, err := repo.Apply(context.Background(), cmd)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 3)
+}
+
+func TestWithObserverAsyncFull(t *testing.T) {
+	var failures []error
+
+	release := make(chan struct{})
+	observer := eventsource.ObserverFunc(func(ctx context.Context, version int, event eventsource.Event) error {
+		<-release
+		return nil
+	})
+	onError := func(ctx context.Context, event eventsource.Event, err error) {
+		failures = append(failures, err)
+	}
+
+	repo := eventsource.New(&Entity{},
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{})),
+		eventsource.WithObserver(observer, eventsource.Async(0), eventsource.OnError(onError)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "abc"}}
+
+	// the first event is picked up by the observer, the second has nowhere to go
+	_, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+
+	cancel()
+	_, err = repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, []error{context.Canceled}, failures)
+
+	close(release)
+	assert.Nil(t, repo.Close())
+}
+
+func TestWithObserverCloses(t *testing.T) {
+	var repo *eventsource.Repository
+	observer := eventsource.ObserverFunc(func(ctx context.Context, version int, event eventsource.Event) error {
+		return repo.Close()
+	})
+
+	var failures []error
+	onError := func(ctx context.Context, event eventsource.Event, err error) {
+		failures = append(failures, err)
+	}
+
+	repo = eventsource.New(&Entity{},
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{})),
+		eventsource.WithObserver(observer),
+		eventsource.WithObserver(eventsource.ObserverFunc(func(ctx context.Context, version int, event eventsource.Event) error {
+			return nil
+		}), eventsource.Async(1), eventsource.OnError(onError)),
+	)
+
+	// a synchronous observer calling Close must not deadlock Apply
+	done := make(chan error, 1)
+	go func() {
+		_, err := repo.Apply(context.Background(), &CreateEntity{CommandModel: eventsource.CommandModel{ID: "abc"}})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Apply")
+	}
+
+	// the repository closed before the event reached the async observer
+	assert.Len(t, failures, 1)
+}
@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/altairsix/eventsource"
+)
+
+// Publisher delivers an outbox record to a downstream system e.g. a message broker
+type Publisher interface {
+	// Publish returns nil once the record has been accepted by the downstream system.  Records may
+	// be published more than once so the downstream system should tolerate duplicates
+	Publish(ctx context.Context, record eventsource.StreamRecord) error
+}
+
+// PublisherFunc provides a func alternative to Publisher
+type PublisherFunc func(ctx context.Context, record eventsource.StreamRecord) error
+
+// Publish implements the Publisher interface
+func (fn PublisherFunc) Publish(ctx context.Context, record eventsource.StreamRecord) error {
+	return fn(ctx, record)
+}
+
+// Source provides access to the records held in an outbox; pgstore.Outbox and mysqlstore.Outbox
+// both implement Source
+type Source interface {
+	// Pending returns up to limit undelivered records in the order they were written.  The
+	// Offset of each record uniquely identifies it within the outbox
+	Pending(ctx context.Context, limit int) ([]eventsource.StreamRecord, error)
+
+	// MarkDelivered marks the records with the specified offsets as delivered
+	MarkDelivered(ctx context.Context, offsets ...uint64) error
+}
+
+// Memory provides an in-memory Source suitable for testing
+type Memory struct {
+	mux       sync.Mutex
+	offset    uint64
+	pending   map[uint64]eventsource.StreamRecord
+	delivered []eventsource.StreamRecord
+}
+
+// Append adds the records for the specified aggregate to the outbox
+func (m *Memory) Append(aggregateID string, records ...eventsource.Record) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, record := range records {
+		m.offset++
+		m.pending[m.offset] = eventsource.StreamRecord{
+			AggregateID: aggregateID,
+			Offset:      m.offset,
+			Record:      record,
+		}
+	}
+}
+
+// Pending implements the Source interface
+func (m *Memory) Pending(ctx context.Context, limit int) ([]eventsource.StreamRecord, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	records := make([]eventsource.StreamRecord, 0, len(m.pending))
+	for _, record := range m.pending {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Offset < records[j].Offset
+	})
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+// MarkDelivered implements the Source interface
+func (m *Memory) MarkDelivered(ctx context.Context, offsets ...uint64) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, offset := range offsets {
+		if record, ok := m.pending[offset]; ok {
+			m.delivered = append(m.delivered, record)
+			delete(m.pending, offset)
+		}
+	}
+
+	return nil
+}
+
+// Delivered returns the records that have been marked as delivered, in the order they were marked
+func (m *Memory) Delivered() []eventsource.StreamRecord {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return append([]eventsource.StreamRecord(nil), m.delivered...)
+}
+
+// NewMemory returns a new, empty in-memory outbox
+func NewMemory() *Memory {
+	return &Memory{
+		pending: map[uint64]eventsource.StreamRecord{},
+	}
+}
@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is the number of records read from the outbox at a time by default
+	DefaultBatchSize = 100
+
+	// DefaultInterval is how long the relay waits by default before polling an empty outbox again
+	DefaultInterval = time.Second
+)
+
+// Option provides functional configuration for a *Relay
+type Option func(*Relay)
+
+// WithBatchSize specifies the maximum number of records read from the outbox at a time
+func WithBatchSize(n int) Option {
+	return func(r *Relay) {
+		if n > 0 {
+			r.batchSize = n
+		}
+	}
+}
+
+// WithInterval specifies how long Run waits before polling the outbox again once the outbox is
+// empty or an error has occurred
+func WithInterval(d time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = d
+	}
+}
+
+// WithDebug will generate additional logging useful for debugging
+func WithDebug(w io.Writer) Option {
+	return func(r *Relay) {
+		r.writer = w
+		r.debug = true
+	}
+}
+
+// Relay moves records from an outbox Source to a Publisher.  Records are marked as delivered
+// only after they have been published, so delivery is at-least-once: a crash between the two
+// results in the record being published again.  Only one Relay should run per outbox at a time
+// otherwise records will be published more than once
+type Relay struct {
+	source    Source
+	publisher Publisher
+	batchSize int
+	interval  time.Duration
+	writer    io.Writer
+	debug     bool
+}
+
+func (r *Relay) logf(format string, args ...interface{}) {
+	if !r.debug {
+		return
+	}
+
+	now := time.Now().Format(time.StampMilli)
+	io.WriteString(r.writer, now)
+	io.WriteString(r.writer, " ")
+
+	fmt.Fprintf(r.writer, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		io.WriteString(r.writer, "\n")
+	}
+}
+
+// RelayOnce publishes a single batch of pending records, in order, and returns the number of
+// records delivered.  Publishing stops at the first record that fails so that ordering is
+// preserved; records published before the failure are still marked as delivered
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	records, err := r.source.Pending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	offsets := make([]uint64, 0, len(records))
+	var publishErr error
+	for _, record := range records {
+		if publishErr = r.publisher.Publish(ctx, record); publishErr != nil {
+			r.logf("Unable to publish version %v of aggregate id, %v: %v", record.Version, record.AggregateID, publishErr)
+			break
+		}
+		offsets = append(offsets, record.Offset)
+	}
+
+	if err := r.source.MarkDelivered(ctx, offsets...); err != nil {
+		return 0, err
+	}
+
+	return len(offsets), publishErr
+}
+
+// Run relays records until the context is cancelled
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			r.logf("Relay failed after delivering %v record(s): %v", n, err)
+		} else if n > 0 {
+			r.logf("Delivered %v record(s)", n)
+		}
+
+		// keep going while there are records to be delivered
+		if err == nil && n == r.batchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		timer := time.NewTimer(r.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// NewRelay returns a new Relay that publishes the records from source to publisher
+func NewRelay(source Source, publisher Publisher, opts ...Option) *Relay {
+	r := &Relay{
+		source:    source,
+		publisher: publisher,
+		batchSize: DefaultBatchSize,
+		interval:  DefaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
@@ -0,0 +1,73 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayOnce(t *testing.T) {
+	ctx := context.Background()
+	source := outbox.NewMemory()
+	source.Append("abc",
+		eventsource.Record{Version: 1, Data: []byte("a")},
+		eventsource.Record{Version: 2, Data: []byte("b")},
+		eventsource.Record{Version: 3, Data: []byte("c")},
+	)
+
+	var published []int
+	fail := true
+	publisher := outbox.PublisherFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if record.Version == 2 && fail {
+			fail = false
+			return errors.New("boom")
+		}
+		published = append(published, record.Version)
+		return nil
+	})
+
+	relay := outbox.NewRelay(source, publisher, outbox.WithBatchSize(10))
+
+	// publishing stops at the first failure
+	n, err := relay.RelayOnce(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int{1}, published)
+	assert.Len(t, source.Delivered(), 1)
+
+	// the failed record is retried
+	n, err = relay.RelayOnce(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int{1, 2, 3}, published)
+	assert.Len(t, source.Delivered(), 3)
+
+	pending, err := source.Pending(ctx, 10)
+	assert.Nil(t, err)
+	assert.Len(t, pending, 0)
+}
+
+func TestRelayRun(t *testing.T) {
+	source := outbox.NewMemory()
+	for i := 1; i <= 5; i++ {
+		source.Append("abc", eventsource.Record{Version: i})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	publisher := outbox.PublisherFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if record.Version == 5 {
+			cancel()
+		}
+		return nil
+	})
+
+	relay := outbox.NewRelay(source, publisher, outbox.WithBatchSize(2), outbox.WithInterval(time.Hour))
+	err := relay.Run(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, source.Delivered(), 5)
+}
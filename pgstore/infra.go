@@ -13,9 +13,15 @@ const (
 		id           SERIAL PRIMARY KEY,
 		aggregate_id VARCHAR(255) NOT NULL,
 		data         BYTEA,
-		version      INT
+		version      INT,
+		metadata     BYTEA
 	);
 `
+
+	// AddMetadataSQL provides sql to add the metadata column to tables created before it existed
+	AddMetadataSQL = `
+	ALTER TABLE ${TABLE} ADD COLUMN IF NOT EXISTS metadata BYTEA;
+`
 	// CheckIndexSQL provides sql to query db to determine whether the index exists
 	CheckIndexSQL = `
 	SELECT count(*)
@@ -30,6 +36,33 @@ const (
 	CREATE UNIQUE INDEX idx_${TABLE}
 	ON ${TABLE} (aggregate_id, version);
 `
+
+	// CreateSnapshotSQL provides sql to create the snapshot table
+	CreateSnapshotSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		aggregate_id VARCHAR(255) PRIMARY KEY,
+		version      INT NOT NULL,
+		data         BYTEA
+	);
+`
+	// CreateOutboxSQL provides sql to create the outbox table
+	CreateOutboxSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		id           SERIAL PRIMARY KEY,
+		aggregate_id VARCHAR(255) NOT NULL,
+		version      INT NOT NULL,
+		data         BYTEA,
+		metadata     BYTEA,
+		delivered_at TIMESTAMP
+	);
+`
+	// CreateCheckpointSQL provides sql to create the subscription checkpoint table
+	CreateCheckpointSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		name     VARCHAR(255) PRIMARY KEY,
+		position BIGINT NOT NULL
+	);
+`
 )
 
 func expand(template, tableName string) string {
@@ -43,6 +76,11 @@ func CreateIfNotExists(db DB, tableName string) error {
 		return errors.Wrap(err, "unable to create table")
 	}
 
+	_, err = db.Exec(expand(AddMetadataSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to add metadata column")
+	}
+
 	row, err := db.Query(expand(CheckIndexSQL, tableName))
 	if err != nil {
 		return errors.Wrap(err, "query failed to determine if index exists")
@@ -66,3 +104,33 @@ func CreateIfNotExists(db DB, tableName string) error {
 
 	return err
 }
+
+// CreateSnapshotIfNotExists creates the specified snapshot table in the db if it does not already exist
+func CreateSnapshotIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateSnapshotSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create snapshot table")
+	}
+
+	return nil
+}
+
+// CreateOutboxIfNotExists creates the specified outbox table in the db if it does not already exist
+func CreateOutboxIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateOutboxSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create outbox table")
+	}
+
+	return nil
+}
+
+// CreateCheckpointIfNotExists creates the specified checkpoint table in the db if it does not already exist
+func CreateCheckpointIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateCheckpointSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create checkpoint table")
+	}
+
+	return nil
+}
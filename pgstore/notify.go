@@ -0,0 +1,119 @@
+package pgstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	// notifySQL sends an empty payload; listeners only need to know that records may be available
+	// and postgres collapses identical notifications sent within a single transaction
+	notifySQL = `SELECT pg_notify($1, '')`
+
+	// listenerPingInterval is how often an idle Listener checks its connection is still alive
+	listenerPingInterval = 90 * time.Second
+)
+
+// notify sends a notification on the store's channel; postgres holds the notification until the
+// transaction commits
+func (s *Store) notify(ctx context.Context, tx DB) error {
+	stmt, err := tx.PrepareContext(ctx, notifySQL)
+	if err != nil {
+		return errors.Wrap(err, "unable to prepare notify statement")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(s.channel); err != nil {
+		return errors.Wrapf(err, "unable to notify channel, %v", s.channel)
+	}
+
+	return nil
+}
+
+// Listener receives the notifications sent by a Store configured WithNotify and wakes any number
+// of subscribers.  Wake ups are also sent whenever the underlying connection is re-established
+// as notifications may have been missed while it was down
+type Listener struct {
+	listener *pq.Listener
+
+	mux      sync.Mutex
+	channels []chan struct{}
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Notify returns a channel that receives a value whenever new records may be available.  Wake ups
+// are coalesced so a slow receiver sees at most one pending value.  Suitable for
+// subscription.WithNotify
+func (l *Listener) Notify() <-chan struct{} {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	ch := make(chan struct{}, 1)
+	l.channels = append(l.channels, ch)
+	return ch
+}
+
+func (l *Listener) wake() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for _, ch := range l.channels {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *Listener) run() {
+	defer l.wg.Done()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-l.listener.Notify:
+			// a nil notification indicates the connection was re-established
+			l.wake()
+		case <-time.After(listenerPingInterval):
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close stops listening and releases the connection; may be called more than once
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.wg.Wait()
+		l.closeErr = l.listener.Close()
+	})
+	return l.closeErr
+}
+
+// NewListener returns a Listener for the notifications sent on channel by a Store configured
+// WithNotify.  dsn is a connection string understood by github.com/lib/pq
+func NewListener(dsn, channel string) (*Listener, error) {
+	listener := pq.NewListener(dsn, 100*time.Millisecond, 10*time.Second, nil)
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, errors.Wrapf(err, "unable to listen on channel, %v", channel)
+	}
+
+	l := &Listener{
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	l.wg.Add(1)
+	go l.run()
+
+	return l, nil
+}
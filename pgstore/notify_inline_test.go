@@ -0,0 +1,21 @@
+package pgstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerClose_Inline(t *testing.T) {
+	l := &Listener{
+		listener: pq.NewListener("postgres://localhost:1/none?sslmode=disable", time.Millisecond, time.Millisecond, nil),
+		done:     make(chan struct{}),
+	}
+	l.wg.Add(1)
+	go l.run()
+
+	assert.Nil(t, l.Close())
+	assert.Nil(t, l.Close())
+}
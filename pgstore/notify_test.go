@@ -0,0 +1,69 @@
+package pgstore_test
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/pgstore"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Notify(t *testing.T) {
+	// notifications are only delivered on commit so this test cannot run inside WithRollback
+	db, err := sql.Open("postgres", dsn)
+	if !assert.Nil(t, err, "unable to open connection") {
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatalf("unable to connect to postgres, %v", err)
+	}
+
+	tableName := "notify_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	channel := tableName + "_events"
+	assert.Nil(t, pgstore.CreateIfNotExists(db, tableName))
+	defer db.Exec("DROP TABLE " + tableName)
+
+	listener, err := pgstore.NewListener(dsn, channel)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer listener.Close()
+
+	ctx := context.Background()
+	store, err := pgstore.New(tableName, Accessor{db: db}, pgstore.WithNotify(channel))
+	assert.Nil(t, err)
+
+	handled := make(chan eventsource.StreamRecord, 10)
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		handled <- record
+		return nil
+	})
+
+	// the poll interval is long enough that only a notification can deliver the record promptly
+	sub := subscription.New("sample", store, handler,
+		subscription.WithPollInterval(time.Hour),
+		subscription.WithNotify(listener.Notify()),
+	)
+	done := make(chan error)
+	go func() { done <- sub.Run(ctx) }()
+
+	err = store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+	assert.Nil(t, err)
+
+	select {
+	case record := <-handled:
+		assert.Equal(t, "abc", record.AggregateID)
+		assert.Equal(t, 1, record.Version)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	sub.Stop()
+	assert.Nil(t, <-done)
+}
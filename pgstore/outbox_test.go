@@ -0,0 +1,57 @@
+package pgstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/outbox"
+	"github.com/altairsix/eventsource/pgstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox_ImplementsSource(t *testing.T) {
+	v, err := pgstore.NewOutbox("blah", nil)
+	assert.Nil(t, err)
+
+	var source outbox.Source = v
+	assert.NotNil(t, source)
+}
+
+func TestStore_SaveWithOutbox(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		outboxTable := tableName + "_outbox"
+		store, err := pgstore.New(tableName, Accessor{db: db}, pgstore.WithOutbox(outboxTable))
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{Version: 1, Data: []byte("a"), Metadata: eventsource.Metadata{eventsource.ActorKey: "joe"}},
+			{Version: 2, Data: []byte("b")},
+		}
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		source, err := pgstore.NewOutbox(outboxTable, Accessor{db: db})
+		assert.Nil(t, err)
+
+		var published []eventsource.StreamRecord
+		publisher := outbox.PublisherFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+			published = append(published, record)
+			return nil
+		})
+
+		n, err := outbox.NewRelay(source, publisher).RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+		assert.Len(t, published, 2)
+		assert.Equal(t, aggregateID, published[0].AggregateID)
+		assert.Equal(t, history[0], published[0].Record)
+		assert.Equal(t, history[1], published[1].Record)
+
+		pending, err := source.Pending(ctx, 10)
+		assert.Nil(t, err)
+		assert.Len(t, pending, 0)
+	})
+}
@@ -0,0 +1,84 @@
+package pgstore
+
+import (
+	"context"
+	"strings"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	saveSnapshotSQL = `INSERT INTO ${TABLE} (aggregate_id, version, data) VALUES ($1, $2, $3)
+		ON CONFLICT (aggregate_id) DO UPDATE SET version = EXCLUDED.version, data = EXCLUDED.data
+		WHERE ${TABLE}.version < EXCLUDED.version`
+	loadSnapshotSQL = `SELECT version, data FROM ${TABLE} WHERE aggregate_id = $1`
+)
+
+// SnapshotStore provides an eventsource.SnapshotStore implementation backed by postgres
+type SnapshotStore struct {
+	tableName string
+	accessor  Accessor
+}
+
+func (s *SnapshotStore) expand(statement string) string {
+	return strings.Replace(statement, "${TABLE}", s.tableName, -1)
+}
+
+// SaveSnapshot saves the snapshot unless a more recent one has already been saved
+func (s *SnapshotStore) SaveSnapshot(ctx context.Context, aggregateID string, snapshot eventsource.Snapshot) error {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save snapshot failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	stmt, err := db.PrepareContext(ctx, s.expand(saveSnapshotSQL))
+	if err != nil {
+		return errors.Wrap(err, "unable to prepare statement")
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(aggregateID, snapshot.Version, snapshot.Data)
+	if err != nil {
+		return errors.Wrapf(err, "unable to save snapshot for aggregate, %v", aggregateID)
+	}
+
+	return nil
+}
+
+// LoadSnapshot returns the most recent snapshot for the aggregate
+func (s *SnapshotStore) LoadSnapshot(ctx context.Context, aggregateID string) (eventsource.Snapshot, error) {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return eventsource.Snapshot{}, errors.Wrap(err, "load snapshot failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	rows, err := db.Query(s.expand(loadSnapshotSQL), aggregateID)
+	if err != nil {
+		return eventsource.Snapshot{}, errors.Wrap(err, "load snapshot failed; unable to query rows")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return eventsource.Snapshot{}, eventsource.NewError(rows.Err(), eventsource.ErrSnapshotNotFound, "no snapshot found with id, %v", aggregateID)
+	}
+
+	snapshot := eventsource.Snapshot{}
+	if err := rows.Scan(&snapshot.Version, &snapshot.Data); err != nil {
+		return eventsource.Snapshot{}, errors.Wrap(err, "load snapshot failed; unable to parse row")
+	}
+
+	return snapshot, nil
+}
+
+// NewSnapshotStore returns a new postgres backed eventsource.SnapshotStore
+func NewSnapshotStore(tableName string, accessor Accessor) (*SnapshotStore, error) {
+	store := &SnapshotStore{
+		tableName: tableName,
+		accessor:  accessor,
+	}
+
+	return store, nil
+}
@@ -0,0 +1,48 @@
+package pgstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/pgstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStore_ImplementsSnapshotStore(t *testing.T) {
+	v, err := pgstore.NewSnapshotStore("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.SnapshotStore = v
+	assert.NotNil(t, store)
+}
+
+func TestSnapshotStore_SaveAndLoad(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := pgstore.NewSnapshotStore(tableName+"_snapshots", Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		_, err = store.LoadSnapshot(ctx, aggregateID)
+		assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrSnapshotNotFound))
+
+		err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		// older snapshots are ignored
+		err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+
+		snapshot, err := store.LoadSnapshot(ctx, aggregateID)
+		assert.Nil(t, err)
+		assert.Equal(t, eventsource.Snapshot{Version: 2, Data: []byte("b")}, snapshot)
+
+		err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 3, Data: []byte("c")})
+		assert.Nil(t, err)
+
+		snapshot, err = store.LoadSnapshot(ctx, aggregateID)
+		assert.Nil(t, err)
+		assert.Equal(t, eventsource.Snapshot{Version: 3, Data: []byte("c")}, snapshot)
+	})
+}
@@ -5,18 +5,22 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
-	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/internal/sqltx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
 const (
-	insertSQL = `INSERT INTO ${TABLE} (aggregate_id, data, version) VALUES ($1, $2, $3)`
-	selectSQL = `SELECT data, version FROM ${TABLE} WHERE aggregate_id = $1 AND version >= $2 AND version <= $3 ORDER BY version ASC`
-	readSQL   = `SELECT id, aggregate_id, data, version FROM ${TABLE} WHERE id >= $1 ORDER BY ID LIMIT $2`
+	insertSQL = `INSERT INTO ${TABLE} (aggregate_id, data, version, metadata) VALUES ($1, $2, $3, $4)`
+	selectSQL = `SELECT data, version, metadata FROM ${TABLE} WHERE aggregate_id = $1 AND version >= $2 AND version <= $3 ORDER BY version ASC`
+	readSQL   = `SELECT id, aggregate_id, data, version, metadata FROM ${TABLE} WHERE id >= $1 ORDER BY ID LIMIT $2`
+
+	// pqUniqueViolation is the postgres error code for unique_violation
+	pqUniqueViolation = "23505"
 )
 
 // DB provides a smaller surface area for the db calls used; Exec is only used by the create function
@@ -42,6 +46,28 @@ type Accessor interface {
 type Store struct {
 	tableName string
 	accessor  Accessor
+	outbox    string
+	channel   string
+}
+
+// Option provides functional configuration for a *Store
+type Option func(*Store)
+
+// WithOutbox causes each saved record to also be written to the specified outbox table within
+// the same transaction as the event itself.  See Outbox for reading the records back
+func WithOutbox(tableName string) Option {
+	return func(s *Store) {
+		s.outbox = tableName
+	}
+}
+
+// WithNotify causes the store to send a NOTIFY on the specified channel whenever records are saved.
+// The payload is empty; it only signals that records may be available.  Notifications are only
+// delivered once the transaction commits.  See Listener for receiving them
+func WithNotify(channel string) Option {
+	return func(s *Store) {
+		s.channel = channel
+	}
 }
 
 func (s *Store) expand(statement string) string {
@@ -79,18 +105,110 @@ func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventso
 	}
 	defer s.accessor.Close(db)
 
-	maxVersion, err := s.maxVersion(ctx, db, aggregateID)
+	items := append(eventsource.History(nil), records...)
+	sort.Sort(items)
+
+	// the version check runs in the same transaction as the insert so a concurrent writer is
+	// caught by the unique index rather than silently interleaving
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion >= items[0].Version {
+			return s.isIdempotent(ctx, tx, aggregateID, items...)
+		}
+
+		return s.insertTx(ctx, tx, aggregateID, items...)
+	})
+}
+
+// SaveVersion saves the provided records only if the aggregate is currently at expectedVersion; implements
+// eventsource.VersionedStore
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db, err := s.accessor.Open(ctx)
 	if err != nil {
 		return errors.Wrap(err, "save failed; unable to connect to db")
 	}
+	defer s.accessor.Close(db)
 
-	items := eventsource.History(records)
-	sort.Sort(items)
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion != expectedVersion {
+			return s.isIdempotent(ctx, tx, aggregateID, records...)
+		}
+
+		return s.insertTx(ctx, tx, aggregateID, records...)
+	})
+}
 
-	if maxVersion >= items[0].Version {
-		return s.isIdempotent(ctx, db, aggregateID, records...)
+// SaveAll saves the records of several aggregates within a single transaction; implements
+// eventsource.BatchStore
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		for _, batch := range batches {
+			if len(batch.Records) == 0 {
+				continue
+			}
+
+			maxVersion, err := s.maxVersion(ctx, tx, batch.AggregateID)
+			if err != nil {
+				return errors.Wrap(err, "save failed; unable to connect to db")
+			}
+
+			items := append(eventsource.History(nil), batch.Records...)
+			sort.Sort(items)
+
+			if maxVersion >= items[0].Version {
+				if err := s.isIdempotent(ctx, tx, batch.AggregateID, items...); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.insertTx(ctx, tx, batch.AggregateID, items...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// insertTx inserts the records and, if configured, the corresponding outbox records and notification
+func (s *Store) insertTx(ctx context.Context, tx DB, aggregateID string, records ...eventsource.Record) error {
+	if err := s.insertRecords(ctx, tx, aggregateID, records...); err != nil {
+		return err
+	}
+	if s.outbox != "" {
+		if err := insertOutbox(ctx, tx, s.outbox, aggregateID, records...); err != nil {
+			return err
+		}
+	}
+	if s.channel != "" {
+		if err := s.notify(ctx, tx); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+func (s *Store) insertRecords(ctx context.Context, db DB, aggregateID string, records ...eventsource.Record) error {
 	stmt, err := db.PrepareContext(ctx, s.expand(insertSQL))
 	if err != nil {
 		return errors.Wrap(err, "unable to prepare statement")
@@ -98,14 +216,30 @@ func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventso
 	defer stmt.Close()
 
 	for _, record := range records {
-		_, err = stmt.Exec(aggregateID, record.Data, record.Version)
+		metadata, err := eventsource.MarshalMetadata(record.Metadata)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal metadata for version %v of aggregate, %v", record.Version, aggregateID)
+		}
+
+		_, err = stmt.Exec(aggregateID, record.Data, record.Version, metadata)
 		if err != nil {
+			if s.isVersionConflict(err) {
+				return eventsource.NewError(err, eventsource.ErrConcurrencyConflict, "unable to save records; version %v of aggregate, %v, already exists", record.Version, aggregateID)
+			}
+			return errors.Wrapf(err, "unable to insert version %v for aggregate, %v", record.Version, aggregateID)
 		}
 	}
 
 	return nil
 }
 
+// isVersionConflict returns true if err is a unique violation of the (aggregate_id, version) index
+// i.e. another writer saved the same version first
+func (s *Store) isVersionConflict(err error) bool {
+	v, ok := errors.Cause(err).(*pq.Error)
+	return ok && v.Code == pqUniqueViolation && v.Constraint == "idx_"+s.tableName
+}
+
 func (s *Store) isIdempotent(ctx context.Context, db DB, aggregateID string, records ...eventsource.Record) error {
 	segments := eventsource.History(records)
 	sort.Sort(segments)
@@ -117,8 +251,8 @@ func (s *Store) isIdempotent(ctx context.Context, db DB, aggregateID string, rec
 		return fmt.Errorf("unable to retrieve version %v-%v for aggregate, %v", fromVersion, toVersion, aggregateID)
 	}
 
-	if !reflect.DeepEqual(segments, loaded) {
-		return fmt.Errorf("unable to save records; conflicting records detected for aggregate, %v", aggregateID)
+	if !segments.SameEvents(loaded) {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
 	}
 
 	return nil
@@ -146,15 +280,23 @@ func (s *Store) doLoad(ctx context.Context, db DB, aggregateID string, initialVe
 	if err != nil {
 		return nil, errors.Wrap(err, "load failed; unable to query rows")
 	}
+	defer rows.Close()
 
 	history := eventsource.History{}
 	for rows.Next() {
 		record := eventsource.Record{}
-		if err := rows.Scan(&record.Data, &record.Version); err != nil {
+		var metadata []byte
+		if err := rows.Scan(&record.Data, &record.Version, &metadata); err != nil {
 			return nil, errors.Wrap(err, "load failed; unable to parse row")
 		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrap(err, "load failed; unable to parse metadata")
+		}
 		history = append(history, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to read rows")
+	}
 
 	return history, nil
 }
@@ -175,21 +317,32 @@ func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int
 
 	for rows.Next() {
 		record := eventsource.StreamRecord{}
-		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Data, &record.Version); err != nil {
+		var metadata []byte
+		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Data, &record.Version, &metadata); err != nil {
 			return nil, errors.Wrapf(err, "failed to scan stream record from db")
 		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse metadata of stream record")
+		}
 		records = append(records, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "read failed; unable to read records from db")
+	}
 
 	return records, nil
 }
 
 // New returns a new postgres backed eventsource.Store
-func New(tableName string, accessor Accessor) (*Store, error) {
+func New(tableName string, accessor Accessor, opts ...Option) (*Store, error) {
 	store := &Store{
 		tableName: tableName,
 		accessor:  accessor,
 	}
 
+	for _, opt := range opts {
+		opt(store)
+	}
+
 	return store, nil
 }
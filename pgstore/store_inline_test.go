@@ -0,0 +1,18 @@
+package pgstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVersionConflict_Inline(t *testing.T) {
+	s := &Store{tableName: "sample"}
+
+	assert.True(t, s.isVersionConflict(&pq.Error{Code: pqUniqueViolation, Constraint: "idx_sample"}))
+	assert.False(t, s.isVersionConflict(&pq.Error{Code: pqUniqueViolation, Constraint: "sample_pkey"}))
+	assert.False(t, s.isVersionConflict(&pq.Error{Code: "23503", Constraint: "idx_sample"}))
+	assert.False(t, s.isVersionConflict(errors.New("boom")))
+}
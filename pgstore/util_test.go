@@ -51,6 +51,18 @@ func WithRollback(t *testing.T, fn func(db DB, tableName string)) {
 		t.Errorf("unable to create table, %v", err)
 		return
 	}
+	if err := pgstore.CreateSnapshotIfNotExists(db, tableName+"_snapshots"); err != nil {
+		t.Errorf("unable to create snapshot table, %v", err)
+		return
+	}
+	if err := pgstore.CreateOutboxIfNotExists(db, tableName+"_outbox"); err != nil {
+		t.Errorf("unable to create outbox table, %v", err)
+		return
+	}
+	if err := pgstore.CreateCheckpointIfNotExists(db, tableName+"_checkpoints"); err != nil {
+		t.Errorf("unable to create checkpoint table, %v", err)
+		return
+	}
 	defer db.Close()
 
 	tx, err := db.Begin()
@@ -0,0 +1,133 @@
+package projection
+
+import (
+	"context"
+	"sync"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/pkg/errors"
+)
+
+// Projection builds a read model from the event stream
+type Projection interface {
+	// Name uniquely identifies the projection; it is used as the name of the checkpoint
+	Name() string
+
+	// Register binds the projection's handlers to the router
+	Register(router *Router)
+
+	// Reset discards the read model so that it may be rebuilt from the start of the stream
+	Reset(ctx context.Context) error
+}
+
+// Option provides functional configuration for a *Runner
+type Option func(*Runner)
+
+// WithCheckpointStore specifies where the runner saves its progress; by default progress is kept
+// in memory and lost on restart
+func WithCheckpointStore(checkpoints subscription.CheckpointStore) Option {
+	return func(r *Runner) {
+		r.checkpoints = checkpoints
+	}
+}
+
+// WithSubscriptionOptions passes options through to the underlying subscription e.g. batch size
+// or poll interval
+func WithSubscriptionOptions(opts ...subscription.Option) Option {
+	return func(r *Runner) {
+		r.opts = append(r.opts, opts...)
+	}
+}
+
+// Runner feeds a Projection from a StreamReader.  Each record is decoded using the Serializer
+// and routed to the handler registered for its type.  Records whose type is not bound to the
+// Serializer or has no handler are skipped
+type Runner struct {
+	projection  Projection
+	reader      eventsource.StreamReader
+	serializer  eventsource.Serializer
+	checkpoints subscription.CheckpointStore
+	opts        []subscription.Option
+	router      *Router
+
+	mux          sync.Mutex
+	subscription *subscription.Subscription
+}
+
+// Handle decodes the record and routes it to the projection; implements subscription.Handler
+func (r *Runner) Handle(ctx context.Context, record eventsource.StreamRecord) error {
+	event, err := r.serializer.UnmarshalEvent(record.Record)
+	if err != nil {
+		if eventsource.ErrHasCode(err, eventsource.ErrUnboundEventType) {
+			return nil
+		}
+		return errors.Wrapf(err, "projection, %v, unable to decode record at offset %v", r.projection.Name(), record.Offset)
+	}
+
+	if record.Metadata != nil {
+		ctx = eventsource.ContextWithMetadata(ctx, record.Metadata)
+	}
+
+	return r.router.Handle(ctx, event)
+}
+
+// Run feeds the projection from its last checkpoint until the context is cancelled, Stop is
+// called, or a handler returns an error
+func (r *Runner) Run(ctx context.Context) error {
+	sub := subscription.New(r.projection.Name(), r.reader, r,
+		append([]subscription.Option{subscription.WithCheckpointStore(r.checkpoints)}, r.opts...)...,
+	)
+
+	r.mux.Lock()
+	r.subscription = sub
+	r.mux.Unlock()
+
+	return sub.Run(ctx)
+}
+
+// Rebuild resets the checkpoint and the read model and then runs the projection from offset 0.
+// The checkpoint is reset first so that a failure part way through never leaves an empty read
+// model behind a checkpoint that skips the records needed to rebuild it.  Rebuild must not be
+// called while the projection is running
+func (r *Runner) Rebuild(ctx context.Context) error {
+	if err := r.checkpoints.SaveCheckpoint(ctx, r.projection.Name(), 0); err != nil {
+		return errors.Wrapf(err, "unable to reset checkpoint for projection, %v", r.projection.Name())
+	}
+
+	if err := r.projection.Reset(ctx); err != nil {
+		return errors.Wrapf(err, "unable to reset projection, %v", r.projection.Name())
+	}
+
+	return r.Run(ctx)
+}
+
+// Stop signals a running projection to stop and blocks until it has done so
+func (r *Runner) Stop() {
+	r.mux.Lock()
+	sub := r.subscription
+	r.mux.Unlock()
+
+	if sub != nil {
+		sub.Stop()
+	}
+}
+
+// New returns a Runner that feeds the projection with records from the reader, decoded using the
+// serializer, typically the Serializer of the Repository that wrote the records
+func New(projection Projection, reader eventsource.StreamReader, serializer eventsource.Serializer, opts ...Option) *Runner {
+	r := &Runner{
+		projection:  projection,
+		reader:      reader,
+		serializer:  serializer,
+		checkpoints: subscription.NewMemoryCheckpointStore(),
+		router:      NewRouter(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	projection.Register(r.router)
+
+	return r
+}
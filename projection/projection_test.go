@@ -0,0 +1,188 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/projection"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+type ItemAdded struct {
+	eventsource.Model
+	Name string
+}
+
+type ItemRemoved struct {
+	eventsource.Model
+	Name string
+}
+
+type ItemIgnored struct {
+	eventsource.Model
+}
+
+// items is a projection that maintains the set of items and who added them
+type items struct {
+	names       map[string]string
+	resets      int
+	checkpoints subscription.CheckpointStore
+	resetAt     []uint64
+}
+
+func (p *items) Name() string {
+	return "items"
+}
+
+func (p *items) Register(router *projection.Router) {
+	router.On(ItemAdded{}, func(ctx context.Context, event eventsource.Event) error {
+		p.names[event.(*ItemAdded).Name] = eventsource.MetadataFromContext(ctx).Actor()
+		return nil
+	})
+	router.On(ItemRemoved{}, func(ctx context.Context, event eventsource.Event) error {
+		delete(p.names, event.(*ItemRemoved).Name)
+		return nil
+	})
+}
+
+func (p *items) Reset(ctx context.Context) error {
+	offset, err := p.checkpoints.LoadCheckpoint(ctx, p.Name())
+	if err != nil {
+		return err
+	}
+	p.resetAt = append(p.resetAt, offset)
+
+	p.names = map[string]string{}
+	p.resets++
+	return nil
+}
+
+// watchedCheckpoints publishes each saved checkpoint so tests can wait on progress
+type watchedCheckpoints struct {
+	subscription.CheckpointStore
+	saved chan uint64
+}
+
+func (w *watchedCheckpoints) SaveCheckpoint(ctx context.Context, name string, offset uint64) error {
+	if err := w.CheckpointStore.SaveCheckpoint(ctx, name, offset); err != nil {
+		return err
+	}
+	w.saved <- offset
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	serializer := eventsource.NewJSONSerializer(ItemAdded{}, ItemRemoved{}, ItemIgnored{})
+
+	var records []eventsource.StreamRecord
+	for _, event := range []eventsource.Event{
+		&ItemAdded{Model: eventsource.Model{ID: "abc", Version: 1}, Name: "a"},
+		&ItemAdded{Model: eventsource.Model{ID: "abc", Version: 2}, Name: "b"},
+		&ItemIgnored{Model: eventsource.Model{ID: "abc", Version: 3}},
+		&ItemRemoved{Model: eventsource.Model{ID: "abc", Version: 4}, Name: "a"},
+	} {
+		record, err := serializer.MarshalEvent(event)
+		assert.Nil(t, err)
+		record.Metadata = eventsource.Metadata{eventsource.ActorKey: "joe"}
+
+		records = append(records, eventsource.StreamRecord{
+			AggregateID: "abc",
+			Offset:      uint64(len(records) + 1),
+			Record:      record,
+		})
+	}
+
+	// include a record whose type the projection knows nothing about
+	records = append(records, eventsource.StreamRecord{
+		AggregateID: "abc",
+		Offset:      uint64(len(records) + 1),
+		Record:      eventsource.Record{Version: 5, Data: []byte(`{"t":"Unknown","d":{}}`)},
+	})
+
+	reader := eventsource.StreamReaderFunc(func(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+		var found []eventsource.StreamRecord
+		for _, record := range records {
+			if record.Offset >= startingOffset && len(found) < recordCount {
+				found = append(found, record)
+			}
+		}
+		return found, nil
+	})
+
+	checkpoints := &watchedCheckpoints{
+		CheckpointStore: subscription.NewMemoryCheckpointStore(),
+		saved:           make(chan uint64, 64),
+	}
+	p := &items{names: map[string]string{}, checkpoints: checkpoints}
+	runner := projection.New(p, reader, serializer,
+		projection.WithCheckpointStore(checkpoints),
+		projection.WithSubscriptionOptions(subscription.WithPollInterval(time.Millisecond)),
+	)
+
+	// run starts the projection and stops it once every record has been checkpointed
+	run := func(fn func(ctx context.Context) error) {
+		done := make(chan error, 1)
+		go func() { done <- fn(context.Background()) }()
+
+		timeout := time.After(10 * time.Second)
+		for offset := uint64(0); offset != 6; {
+			select {
+			case offset = <-checkpoints.saved:
+			case err := <-done:
+				t.Fatalf("projection stopped before reaching the end of the stream: %v", err)
+			case <-timeout:
+				t.Fatal("timed out waiting for the projection to reach the end of the stream")
+			}
+		}
+
+		runner.Stop()
+		assert.Nil(t, <-done)
+	}
+
+	run(runner.Run)
+	assert.Equal(t, map[string]string{"b": "joe"}, p.names)
+
+	offset, err := checkpoints.LoadCheckpoint(context.Background(), "items")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), offset)
+
+	// simulate a read model that has drifted; rebuild replays from the start
+	p.names["z"] = "bob"
+	run(runner.Rebuild)
+	assert.Equal(t, 1, p.resets)
+	assert.Equal(t, map[string]string{"b": "joe"}, p.names)
+
+	// the checkpoint was reset before the read model
+	assert.Equal(t, []uint64{0}, p.resetAt)
+}
+
+func TestRebuildResetFails(t *testing.T) {
+	checkpoints := subscription.NewMemoryCheckpointStore()
+	assert.Nil(t, checkpoints.SaveCheckpoint(context.Background(), "broken", 12))
+
+	reader := eventsource.StreamReaderFunc(func(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+		return nil, nil
+	})
+	runner := projection.New(broken{}, reader, eventsource.NewJSONSerializer(),
+		projection.WithCheckpointStore(checkpoints),
+	)
+	assert.NotNil(t, runner.Rebuild(context.Background()))
+
+	// the projection is left to replay from the start rather than skip past an empty read model
+	offset, err := checkpoints.LoadCheckpoint(context.Background(), "broken")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), offset)
+}
+
+// broken is a projection whose read model cannot be reset
+type broken struct{}
+
+func (broken) Name() string                       { return "broken" }
+func (broken) Register(router *projection.Router) {}
+func (broken) Reset(ctx context.Context) error {
+	return errors.New("boom")
+}
@@ -0,0 +1,44 @@
+package projection
+
+import (
+	"context"
+
+	"github.com/altairsix/eventsource"
+)
+
+// HandlerFunc updates a read model in response to an event.  The context carries the Metadata of
+// the record the event was decoded from; see eventsource.MetadataFromContext
+type HandlerFunc func(ctx context.Context, event eventsource.Event) error
+
+// Router routes events to handlers by event type
+type Router struct {
+	handlers map[string]HandlerFunc
+}
+
+// On registers the handler for events of the same type as the event provided e.g.
+//
+//	router.On(OrderPlaced{}, p.orderPlaced)
+//
+// Registering a second handler for the same type replaces the first
+func (r *Router) On(event eventsource.Event, fn HandlerFunc) {
+	eventType, _ := eventsource.EventType(event)
+	r.handlers[eventType] = fn
+}
+
+// Handle routes the event to its handler; events without a handler are ignored
+func (r *Router) Handle(ctx context.Context, event eventsource.Event) error {
+	eventType, _ := eventsource.EventType(event)
+	fn, ok := r.handlers[eventType]
+	if !ok {
+		return nil
+	}
+
+	return fn(ctx, event)
+}
+
+// NewRouter returns a new, empty Router
+func NewRouter() *Router {
+	return &Router{
+		handlers: map[string]HandlerFunc{},
+	}
+}
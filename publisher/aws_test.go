@@ -0,0 +1,74 @@
+package publisher_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/publisher"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSNS struct {
+	snsiface.SNSAPI
+	inputs []*sns.PublishInput
+}
+
+func (m *mockSNS) PublishWithContext(ctx aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &sns.PublishOutput{}, nil
+}
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	inputs []*sqs.SendMessageInput
+}
+
+func (m *mockSQS) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+var record = eventsource.StreamRecord{
+	AggregateID: "abc",
+	Record:      eventsource.Record{Version: 3, Data: []byte(`{"t":"OrderCreated"}`)},
+}
+
+func TestSNS(t *testing.T) {
+	api := &mockSNS{}
+	err := publisher.NewSNS(api, "topic", publisher.WithSNSFIFO()).Publish(context.Background(), record)
+	assert.Nil(t, err)
+	assert.Len(t, api.inputs, 1)
+
+	input := api.inputs[0]
+	assert.Equal(t, "topic", *input.TopicArn)
+	assert.Equal(t, `{"t":"OrderCreated"}`, *input.Message)
+	assert.Equal(t, "abc", *input.MessageAttributes[publisher.AggregateIDAttribute].StringValue)
+	assert.Equal(t, "3", *input.MessageAttributes[publisher.VersionAttribute].StringValue)
+	assert.Equal(t, "Number", *input.MessageAttributes[publisher.VersionAttribute].DataType)
+	assert.Equal(t, "abc", *input.MessageGroupId)
+	assert.Equal(t, "abc:3", *input.MessageDeduplicationId)
+}
+
+func TestSQS(t *testing.T) {
+	api := &mockSQS{}
+	binary := record
+	binary.Data = []byte{0xff, 0xfe}
+
+	err := publisher.NewSQS(api, "queue").Publish(context.Background(), binary)
+	assert.Nil(t, err)
+	assert.Len(t, api.inputs, 1)
+
+	input := api.inputs[0]
+	assert.Equal(t, "queue", *input.QueueUrl)
+	assert.Equal(t, "//4=", *input.MessageBody)
+	assert.Equal(t, "base64", *input.MessageAttributes[publisher.EncodingAttribute].StringValue)
+	assert.Equal(t, "abc", *input.MessageAttributes[publisher.AggregateIDAttribute].StringValue)
+	assert.Nil(t, input.MessageGroupId)
+}
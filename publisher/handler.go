@@ -0,0 +1,167 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	apex "github.com/apex/go-apex/dynamo"
+	"github.com/pkg/errors"
+)
+
+// BatchItemFailure identifies a stream record that could not be published
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// Response reports the records that could not be published.  When returned from a Lambda function
+// with ReportBatchItemFailures enabled, Lambda retries the batch from the first failed record
+type Response struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+// Option provides functional configuration for a *Handler
+type Option func(*Handler)
+
+// WithHashKey specifies the hash key of the event table; defaults to dynamodbstore.HashKey
+func WithHashKey(hashKey string) Option {
+	return func(h *Handler) {
+		h.hashKey = hashKey
+	}
+}
+
+// WithTableNames restricts the handler to stream records originating from the specified tables;
+// by default records from every table are published
+func WithTableNames(tableNames ...string) Option {
+	return func(h *Handler) {
+		for _, tableName := range tableNames {
+			h.tableNames[tableName] = struct{}{}
+		}
+	}
+}
+
+// WithDebug will generate additional logging useful for debugging
+func WithDebug(w io.Writer) Option {
+	return func(h *Handler) {
+		h.writer = w
+		h.debug = true
+	}
+}
+
+// Handler publishes the events contained in a batch of dynamodb stream records
+type Handler struct {
+	publisher  Publisher
+	hashKey    string
+	tableNames map[string]struct{}
+	writer     io.Writer
+	debug      bool
+}
+
+func (h *Handler) logf(format string, args ...interface{}) {
+	if !h.debug {
+		return
+	}
+
+	now := time.Now().Format(time.StampMilli)
+	io.WriteString(h.writer, now)
+	io.WriteString(h.writer, " ")
+
+	fmt.Fprintf(h.writer, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		io.WriteString(h.writer, "\n")
+	}
+}
+
+// Handle publishes the records in the order they appear in the batch.  Publishing stops at the
+// first record that fails; that record and every record after it are reported in the Response so
+// that they are retried in order
+func (h *Handler) Handle(ctx context.Context, event *apex.Event) (*Response, error) {
+	response := &Response{
+		BatchItemFailures: []BatchItemFailure{},
+	}
+	if event == nil {
+		return response, nil
+	}
+
+	for i, record := range event.Records {
+		if err := h.publish(ctx, record); err != nil {
+			h.logf("Unable to publish stream record, %v: %v", sequenceNumber(record), err)
+			for _, failed := range event.Records[i:] {
+				response.BatchItemFailures = append(response.BatchItemFailures, BatchItemFailure{
+					ItemIdentifier: sequenceNumber(failed),
+				})
+			}
+			break
+		}
+	}
+
+	return response, nil
+}
+
+func (h *Handler) publish(ctx context.Context, record *apex.Record) error {
+	if record == nil || record.Dynamodb == nil {
+		return nil
+	}
+
+	if len(h.tableNames) > 0 {
+		tableName, err := dynamodbstore.TableName(record.EventSourceARN)
+		if err != nil {
+			return err
+		}
+		if _, ok := h.tableNames[tableName]; !ok {
+			return nil
+		}
+	}
+
+	changes, err := dynamodbstore.Changes(record)
+	if err != nil {
+		return errors.Wrap(err, "unable to extract changes from stream record")
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	key, ok := record.Dynamodb.Keys[h.hashKey]
+	if !ok || key.S == nil {
+		return errors.Errorf("stream record does not contain hash key, %v", h.hashKey)
+	}
+
+	for _, change := range changes {
+		err := h.publisher.Publish(ctx, eventsource.StreamRecord{
+			AggregateID: *key.S,
+			Record:      change,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	h.logf("Published %v record(s) for aggregate id, %v", len(changes), *key.S)
+	return nil
+}
+
+func sequenceNumber(record *apex.Record) string {
+	if record == nil || record.Dynamodb == nil {
+		return ""
+	}
+	return record.Dynamodb.SequenceNumber
+}
+
+// NewHandler returns a Handler that publishes events to the publisher provided
+func NewHandler(publisher Publisher, opts ...Option) *Handler {
+	h := &Handler{
+		publisher:  publisher,
+		hashKey:    dynamodbstore.HashKey,
+		tableNames: map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
@@ -0,0 +1,90 @@
+package publisher_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/publisher"
+	apex "github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+const arn = "arn:aws:dynamodb:us-west-2:528688496454:table/orders/stream/2017-03-14T04:49:34.930"
+
+func makeRecord(sequenceNumber, aggregateID string, versions ...int) *apex.Record {
+	image := map[string]*dynamodb.AttributeValue{
+		"key": {S: aws.String(aggregateID)},
+	}
+	for _, version := range versions {
+		image["_"+strconv.Itoa(version)] = &dynamodb.AttributeValue{B: []byte(aggregateID)}
+	}
+
+	return &apex.Record{
+		EventSourceARN: arn,
+		Dynamodb: &apex.StreamRecord{
+			Keys: map[string]*dynamodb.AttributeValue{
+				"key":       {S: aws.String(aggregateID)},
+				"partition": {N: aws.String("0")},
+			},
+			NewImage:       image,
+			SequenceNumber: sequenceNumber,
+		},
+	}
+}
+
+func TestHandler(t *testing.T) {
+	memory := publisher.NewMemory()
+	handler := publisher.NewHandler(memory)
+
+	response, err := handler.Handle(context.Background(), &apex.Event{
+		Records: []*apex.Record{
+			makeRecord("1", "abc", 1, 2),
+			makeRecord("2", "def", 1),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, response.BatchItemFailures, 0)
+	assert.Equal(t, []eventsource.StreamRecord{
+		{AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("abc")}},
+		{AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: []byte("abc")}},
+		{AggregateID: "def", Record: eventsource.Record{Version: 1, Data: []byte("def")}},
+	}, memory.Records())
+}
+
+func TestHandlerPartialFailure(t *testing.T) {
+	memory := publisher.NewMemory()
+	failing := publisher.PublisherFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if record.AggregateID == "def" {
+			return errors.New("boom")
+		}
+		return memory.Publish(ctx, record)
+	})
+
+	response, err := publisher.NewHandler(failing).Handle(context.Background(), &apex.Event{
+		Records: []*apex.Record{
+			makeRecord("1", "abc", 1),
+			makeRecord("2", "def", 1),
+			makeRecord("3", "abc", 2),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []publisher.BatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}, response.BatchItemFailures)
+	assert.Len(t, memory.Records(), 1)
+}
+
+func TestHandlerTableNames(t *testing.T) {
+	memory := publisher.NewMemory()
+	handler := publisher.NewHandler(memory, publisher.WithTableNames("other"))
+
+	response, err := handler.Handle(context.Background(), &apex.Event{
+		Records: []*apex.Record{makeRecord("1", "abc", 1)},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, response.BatchItemFailures, 0)
+	assert.Len(t, memory.Records(), 0)
+}
@@ -0,0 +1,92 @@
+package publisher
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/altairsix/eventsource"
+)
+
+const (
+	// AggregateIDAttribute is the message attribute that holds the aggregate id of the record
+	AggregateIDAttribute = "aggregate_id"
+
+	// VersionAttribute is the message attribute that holds the version of the record
+	VersionAttribute = "version"
+
+	// EncodingAttribute is set to "base64" when the record data is not valid utf-8 and had to be
+	// encoded to be sent as a message body
+	EncodingAttribute = "encoding"
+)
+
+// Publisher delivers a record to a downstream system.  Publisher has the same shape as
+// outbox.Publisher so the implementations in this package may be used by an outbox.Relay
+type Publisher interface {
+	Publish(ctx context.Context, record eventsource.StreamRecord) error
+}
+
+// PublisherFunc provides a func alternative to Publisher
+type PublisherFunc func(ctx context.Context, record eventsource.StreamRecord) error
+
+// Publish implements the Publisher interface
+func (fn PublisherFunc) Publish(ctx context.Context, record eventsource.StreamRecord) error {
+	return fn(ctx, record)
+}
+
+// message holds the body and attributes common to the SNS and SQS publishers
+type message struct {
+	body       string
+	attributes map[string]string
+	numeric    map[string]bool
+}
+
+func makeMessage(record eventsource.StreamRecord) message {
+	m := message{
+		body: string(record.Data),
+		attributes: map[string]string{
+			AggregateIDAttribute: record.AggregateID,
+			VersionAttribute:     strconv.Itoa(record.Version),
+		},
+		numeric: map[string]bool{
+			VersionAttribute: true,
+		},
+	}
+
+	if !utf8.Valid(record.Data) {
+		m.body = base64.StdEncoding.EncodeToString(record.Data)
+		m.attributes[EncodingAttribute] = "base64"
+	}
+
+	return m
+}
+
+// Memory provides an in-memory Publisher suitable for testing
+type Memory struct {
+	mux     sync.Mutex
+	records []eventsource.StreamRecord
+}
+
+// Publish implements the Publisher interface
+func (m *Memory) Publish(ctx context.Context, record eventsource.StreamRecord) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.records = append(m.records, record)
+	return nil
+}
+
+// Records returns the records published so far
+func (m *Memory) Records() []eventsource.StreamRecord {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return append([]eventsource.StreamRecord(nil), m.records...)
+}
+
+// NewMemory returns a new in-memory Publisher
+func NewMemory() *Memory {
+	return &Memory{}
+}
@@ -0,0 +1,76 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/pkg/errors"
+)
+
+// SNS publishes records to an SNS topic.  The record data is sent as the message body and the
+// aggregate id and version as message attributes
+type SNS struct {
+	api      snsiface.SNSAPI
+	topicARN string
+	fifo     bool
+}
+
+// SNSOption provides functional configuration for *SNS
+type SNSOption func(*SNS)
+
+// WithSNSFIFO sets the message group to the aggregate id and deduplicates on aggregate id and
+// version; required when publishing to a FIFO topic
+func WithSNSFIFO() SNSOption {
+	return func(s *SNS) {
+		s.fifo = true
+	}
+}
+
+// Publish implements the Publisher interface
+func (s *SNS) Publish(ctx context.Context, record eventsource.StreamRecord) error {
+	m := makeMessage(record)
+
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(s.topicARN),
+		Message:           aws.String(m.body),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{},
+	}
+	for k, v := range m.attributes {
+		dataType := "String"
+		if m.numeric[k] {
+			dataType = "Number"
+		}
+		input.MessageAttributes[k] = &sns.MessageAttributeValue{
+			DataType:    aws.String(dataType),
+			StringValue: aws.String(v),
+		}
+	}
+	if s.fifo {
+		input.MessageGroupId = aws.String(record.AggregateID)
+		input.MessageDeduplicationId = aws.String(record.AggregateID + ":" + strconv.Itoa(record.Version))
+	}
+
+	if _, err := s.api.PublishWithContext(ctx, input); err != nil {
+		return errors.Wrapf(err, "unable to publish version %v of aggregate, %v, to sns", record.Version, record.AggregateID)
+	}
+
+	return nil
+}
+
+// NewSNS returns a Publisher that publishes to the specified SNS topic
+func NewSNS(api snsiface.SNSAPI, topicARN string, opts ...SNSOption) *SNS {
+	s := &SNS{
+		api:      api,
+		topicARN: topicARN,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
@@ -0,0 +1,76 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/pkg/errors"
+)
+
+// SQS sends records to an SQS queue.  The record data is sent as the message body and the
+// aggregate id and version as message attributes
+type SQS struct {
+	api      sqsiface.SQSAPI
+	queueURL string
+	fifo     bool
+}
+
+// SQSOption provides functional configuration for *SQS
+type SQSOption func(*SQS)
+
+// WithSQSFIFO sets the message group to the aggregate id and deduplicates on aggregate id and
+// version; required when sending to a FIFO queue
+func WithSQSFIFO() SQSOption {
+	return func(s *SQS) {
+		s.fifo = true
+	}
+}
+
+// Publish implements the Publisher interface
+func (s *SQS) Publish(ctx context.Context, record eventsource.StreamRecord) error {
+	m := makeMessage(record)
+
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.queueURL),
+		MessageBody:       aws.String(m.body),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{},
+	}
+	for k, v := range m.attributes {
+		dataType := "String"
+		if m.numeric[k] {
+			dataType = "Number"
+		}
+		input.MessageAttributes[k] = &sqs.MessageAttributeValue{
+			DataType:    aws.String(dataType),
+			StringValue: aws.String(v),
+		}
+	}
+	if s.fifo {
+		input.MessageGroupId = aws.String(record.AggregateID)
+		input.MessageDeduplicationId = aws.String(record.AggregateID + ":" + strconv.Itoa(record.Version))
+	}
+
+	if _, err := s.api.SendMessageWithContext(ctx, input); err != nil {
+		return errors.Wrapf(err, "unable to send version %v of aggregate, %v, to sqs", record.Version, record.AggregateID)
+	}
+
+	return nil
+}
+
+// NewSQS returns a Publisher that sends to the specified SQS queue
+func NewSQS(api sqsiface.SQSAPI, queueURL string, opts ...SQSOption) *SQS {
+	s := &SQS{
+		api:      api,
+		queueURL: queueURL,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,9 +25,18 @@ type Repository struct {
 	prototype  reflect.Type
 	store      Store
 	serializer Serializer
-	observers  []func(Event)
+	observers  []*observer
+	retry      RetryPolicy
+	snapshots  SnapshotStore
+	snapEvery  int
+	cache      *aggregateCache
+	middleware []CommandMiddleware
+	applyFunc  ApplyFunc
 	writer     io.Writer
 	debug      bool
+
+	observerMux sync.RWMutex
+	closed      bool
 }
 
 // Option provides functional configuration for a *Repository
@@ -56,10 +66,47 @@ func WithSerializer(serializer Serializer) Option {
 }
 
 // WithObservers allows observers to watch the saved events; Observers should invoke very short lived operations as
-// calls will block until the observer is finished
+// calls will block until the observer is finished.  See WithObserver for observers that need the context, need to
+// report errors, or should run asynchronously
 func WithObservers(observers ...func(event Event)) Option {
 	return func(r *Repository) {
-		r.observers = append(r.observers, observers...)
+		for _, fn := range observers {
+			fn := fn
+			WithObserver(ObserverFunc(func(ctx context.Context, version int, event Event) error {
+				fn(event)
+				return nil
+			}))(r)
+		}
+	}
+}
+
+// WithRetry causes Apply to reload the aggregate and reapply the command when saving the events fails
+// due to a concurrency conflict.  The policy determines how many attempts will be made and how long
+// to wait between them
+func WithRetry(policy RetryPolicy) Option {
+	return func(r *Repository) {
+		r.retry = policy
+	}
+}
+
+// WithSnapshots allows aggregates that implement Snapshotter to be loaded from the latest snapshot
+// rather than from their full history.  A new snapshot is saved by Apply each time the aggregate
+// version crosses a multiple of every
+func WithSnapshots(store SnapshotStore, every int) Option {
+	return func(r *Repository) {
+		r.snapshots = store
+		r.snapEvery = every
+	}
+}
+
+// WithCache keeps up to size folded aggregates in memory so that subsequent calls to Load and Apply
+// only need to read events newer than the cached version.  Entries older than ttl are discarded; a
+// ttl of 0 means entries never expire.  Aggregates are copied on their way out of the cache, using
+// Snapshotter if implemented or a shallow copy otherwise, so aggregates that do not implement
+// Snapshotter should not modify maps or slices in place
+func WithCache(size int, ttl time.Duration) Option {
+	return func(r *Repository) {
+		r.cache = newAggregateCache(size, ttl)
 	}
 }
 
@@ -79,6 +126,8 @@ func New(prototype Aggregate, opts ...Option) *Repository {
 	for _, opt := range opts {
 		opt(r)
 	}
+	r.applyFunc = chain(applyCommand, r.middleware...)
+	r.startObservers()
 
 	return r
 }
@@ -103,24 +152,115 @@ func (r *Repository) New() Aggregate {
 	return reflect.New(r.prototype).Interface().(Aggregate)
 }
 
-// Save persists the events into the underlying Store
+// Save persists the events into the underlying Store along with any Metadata carried by ctx.  All
+// events must belong to the same aggregate; use SaveAll to save events for several aggregates
 func (r *Repository) Save(ctx context.Context, events ...Event) error {
 	if len(events) == 0 {
 		return nil
 	}
+	aggregateID, err := sameAggregateID(events...)
+	if err != nil {
+		return err
+	}
+
+	history, err := r.marshalAll(ctx, events...)
+	if err != nil {
+		return err
+	}
+
+	return r.store.Save(ctx, aggregateID, history...)
+}
+
+// SaveAll persists events that may belong to several aggregates.  Events are grouped by
+// AggregateID and saved atomically; if the underlying Store does not implement BatchStore and the
+// events span more than one aggregate, an error with code ErrUnsupported is returned
+func (r *Repository) SaveAll(ctx context.Context, events ...Event) error {
+	var (
+		ids     []string
+		grouped = map[string][]Event{}
+	)
+	for _, event := range events {
+		id := event.AggregateID()
+		if _, ok := grouped[id]; !ok {
+			ids = append(ids, id)
+		}
+		grouped[id] = append(grouped[id], event)
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil
+	case 1:
+		return r.Save(ctx, events...)
+	}
+
+	store, ok := r.store.(BatchStore)
+	if !ok {
+		return NewError(nil, ErrUnsupported, "store, %T, is unable to save multiple aggregates atomically", r.store)
+	}
+
+	batches := make([]Batch, 0, len(ids))
+	for _, id := range ids {
+		history, err := r.marshalAll(ctx, grouped[id]...)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, Batch{AggregateID: id, Records: history})
+	}
+
+	return store.SaveAll(ctx, batches...)
+}
+
+// sameAggregateID returns the AggregateID shared by all the events
+func sameAggregateID(events ...Event) (string, error) {
 	aggregateID := events[0].AggregateID()
+	for _, event := range events[1:] {
+		if id := event.AggregateID(); id != aggregateID {
+			return "", fmt.Errorf("events must belong to a single aggregate; found %v and %v", aggregateID, id)
+		}
+	}
+	return aggregateID, nil
+}
 
+// saveVersion persists the events into the underlying Store provided the aggregate is still at
+// the expected version.  If the Store does not implement VersionedStore, saveVersion falls back
+// to Save and relies on the Store to reject duplicate versions.
+func (r *Repository) saveVersion(ctx context.Context, expectedVersion int, events ...Event) error {
+	store, ok := r.store.(VersionedStore)
+	if !ok {
+		return r.Save(ctx, events...)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+	aggregateID, err := sameAggregateID(events...)
+	if err != nil {
+		return err
+	}
+
+	history, err := r.marshalAll(ctx, events...)
+	if err != nil {
+		return err
+	}
+
+	return store.SaveVersion(ctx, aggregateID, expectedVersion, history...)
+}
+
+// marshalAll serializes the events and attaches the metadata carried by ctx
+func (r *Repository) marshalAll(ctx context.Context, events ...Event) (History, error) {
 	history := make(History, 0, len(events))
 	for _, event := range events {
 		record, err := r.serializer.MarshalEvent(event)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		record.Metadata = MetadataFromContext(ctx) // each record owns its copy
 
 		history = append(history, record)
 	}
 
-	return r.store.Save(ctx, aggregateID, history...)
+	return history, nil
 }
 
 // Load retrieves the specified aggregate from the underlying store
@@ -132,20 +272,29 @@ func (r *Repository) Load(ctx context.Context, aggregateID string) (Aggregate, e
 // loadVersion loads the specified aggregate from the store and returns both the Aggregate and the
 // current version number of the aggregate
 func (r *Repository) loadVersion(ctx context.Context, aggregateID string) (Aggregate, int, error) {
-	history, err := r.store.Load(ctx, aggregateID, 0, 0)
-	if err != nil {
+	aggregate, version, ok := r.loadCached(aggregateID)
+	cached := ok
+	if !cached {
+		aggregate, version, ok = r.loadSnapshot(ctx, aggregateID)
+	}
+
+	fromVersion := 0
+	if ok {
+		fromVersion = version + 1
+	}
+
+	history, err := r.store.Load(ctx, aggregateID, fromVersion, 0)
+	if err != nil && !(ok && IsNotFound(err)) {
 		return nil, 0, err
 	}
 
 	entryCount := len(history)
-	if entryCount == 0 {
+	if entryCount == 0 && !ok {
 		return nil, 0, NewError(nil, ErrAggregateNotFound, "unable to load %v, %v", r.New(), aggregateID)
 	}
 
 	r.logf("Loaded %v event(s) for aggregate id, %v", entryCount, aggregateID)
-	aggregate := r.New()
 
-	version := 0
 	for _, record := range history {
 		event, err := r.serializer.UnmarshalEvent(record)
 		if err != nil {
@@ -154,6 +303,9 @@ func (r *Repository) loadVersion(ctx context.Context, aggregateID string) (Aggre
 
 		err = aggregate.On(event)
 		if err != nil {
+			if r.cache != nil {
+				r.cache.remove(aggregateID)
+			}
 			eventType, _ := EventType(event)
 			return nil, 0, NewError(err, ErrUnhandledEvent, "aggregate was unable to handle event, %v", eventType)
 		}
@@ -161,9 +313,95 @@ func (r *Repository) loadVersion(ctx context.Context, aggregateID string) (Aggre
 		version = event.EventVersion()
 	}
 
+	if r.cache != nil && (!cached || entryCount > 0) {
+		dupe, err := r.clone(aggregate)
+		if err != nil {
+			r.logf("Unable to cache aggregate id, %v: %v", aggregateID, err)
+		} else {
+			r.cache.put(aggregateID, dupe, version)
+		}
+	}
+
 	return aggregate, version, nil
 }
 
+// loadSnapshot returns a new aggregate restored from the latest snapshot along with the version of
+// the snapshot.  Snapshots are an optimization so if no usable snapshot exists, a new aggregate is
+// returned along with false and the aggregate will be rebuilt from its full history
+func (r *Repository) loadSnapshot(ctx context.Context, aggregateID string) (Aggregate, int, bool) {
+	aggregate := r.New()
+	if r.snapshots == nil {
+		return aggregate, 0, false
+	}
+
+	snapshotter, ok := aggregate.(Snapshotter)
+	if !ok {
+		return aggregate, 0, false
+	}
+
+	snapshot, err := r.snapshots.LoadSnapshot(ctx, aggregateID)
+	if err != nil {
+		if !ErrHasCode(err, ErrSnapshotNotFound) {
+			r.logf("Unable to load snapshot for aggregate id, %v: %v", aggregateID, err)
+		}
+		return aggregate, 0, false
+	}
+
+	if err := snapshotter.UnmarshalSnapshot(snapshot.Data); err != nil {
+		r.logf("Unable to unmarshal snapshot for aggregate id, %v: %v", aggregateID, err)
+		return r.New(), 0, false
+	}
+
+	r.logf("Loaded snapshot at version %v for aggregate id, %v", snapshot.Version, aggregateID)
+	return aggregate, snapshot.Version, true
+}
+
+// update folds the newly saved events into the aggregate so that it may be snapshotted and cached.
+// Failures are logged rather than returned as the events have already been saved
+func (r *Repository) update(ctx context.Context, aggregate Aggregate, fromVersion, toVersion int, events ...Event) {
+	aggregateID := events[0].AggregateID()
+	snapshotter, ok := aggregate.(Snapshotter)
+	snapshot := ok && r.snapshots != nil && r.snapEvery > 0 && fromVersion/r.snapEvery != toVersion/r.snapEvery
+	if !snapshot && r.cache == nil {
+		return
+	}
+
+	for _, event := range events {
+		if err := aggregate.On(event); err != nil {
+			r.logf("Unable to apply saved events to aggregate id, %v: %v", aggregateID, err)
+			if r.cache != nil {
+				r.cache.remove(aggregateID)
+			}
+			return
+		}
+	}
+
+	if snapshot {
+		r.saveSnapshot(ctx, aggregateID, snapshotter, toVersion)
+	}
+
+	if r.cache != nil {
+		r.cache.put(aggregateID, aggregate, toVersion)
+	}
+}
+
+// saveSnapshot saves a snapshot of the aggregate at the version specified
+func (r *Repository) saveSnapshot(ctx context.Context, aggregateID string, snapshotter Snapshotter, version int) {
+	data, err := snapshotter.MarshalSnapshot()
+	if err != nil {
+		r.logf("Unable to marshal snapshot for aggregate id, %v: %v", aggregateID, err)
+		return
+	}
+
+	err = r.snapshots.SaveSnapshot(ctx, aggregateID, Snapshot{Version: version, Data: data})
+	if err != nil {
+		r.logf("Unable to save snapshot for aggregate id, %v: %v", aggregateID, err)
+		return
+	}
+
+	r.logf("Saved snapshot at version %v for aggregate id, %v", version, aggregateID)
+}
+
 // Dispatch executes the command specified
 //
 // Deprecated: Use Apply instead
@@ -172,8 +410,38 @@ func (r *Repository) Dispatch(ctx context.Context, command Command) error {
 	return err
 }
 
-// Apply executes the command specified and returns the current version of the aggregate
+// Apply executes the command specified and returns the current version of the aggregate.  If the
+// aggregate is modified by another caller between the time it is loaded and the time the events
+// are saved, Apply returns an error with code ErrConcurrencyConflict unless a retry policy was
+// provided via WithRetry
 func (r *Repository) Apply(ctx context.Context, command Command) (int, error) {
+	for attempt := 1; ; attempt++ {
+		version, err := r.apply(ctx, command)
+		if err == nil || r.retry == nil || !IsConcurrencyConflict(err) {
+			if attempt > 1 && err == nil {
+				r.logf("Applied command to aggregate id, %v, after %v attempt(s)", command.AggregateID(), attempt)
+			}
+			return version, err
+		}
+
+		delay, ok := r.retry.Backoff(attempt)
+		if !ok {
+			r.logf("Giving up on aggregate id, %v, after %v attempt(s)", command.AggregateID(), attempt)
+			return 0, NewError(err, ErrConcurrencyConflict, "unable to apply command to aggregate, %v, after %v attempt(s)", command.AggregateID(), attempt)
+		}
+
+		r.logf("Concurrency conflict on aggregate id, %v; attempt %v will be retried in %v", command.AggregateID(), attempt, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *Repository) apply(ctx context.Context, command Command) (int, error) {
 	if command == nil {
 		return 0, errors.New("Command provided to Repository.Dispatch may not be nil")
 	}
@@ -187,32 +455,27 @@ func (r *Repository) Apply(ctx context.Context, command Command) (int, error) {
 		aggregate = r.New()
 	}
 
-	h, ok := aggregate.(CommandHandler)
-	if !ok {
-		return 0, fmt.Errorf("Aggregate, %v, does not implement CommandHandler", aggregate)
-	}
-	events, err := h.Apply(ctx, command)
+	events, err := r.applyFunc(ctx, aggregate, command)
 	if err != nil {
 		return 0, err
 	}
 
-	err = r.Save(ctx, events...)
+	err = r.saveVersion(ctx, version, events...)
 	if err != nil {
+		if r.cache != nil && IsConcurrencyConflict(err) {
+			r.cache.remove(aggregateID)
+		}
 		return 0, err
 	}
 
 	if v := len(events); v > 0 {
+		previous := version
 		version = events[v-1].EventVersion()
+		r.update(ctx, aggregate, previous, version, events...)
 	}
 
 	// publish events to observers
-	if r.observers != nil {
-		for _, event := range events {
-			for _, observer := range r.observers {
-				observer(event)
-			}
-		}
-	}
+	r.notify(ctx, events...)
 
 	return version, nil
 }
@@ -2,6 +2,7 @@ package eventsource_test
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -216,6 +217,48 @@ func TestRepository_SaveNoEvents(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestRepository_SaveMixedAggregates(t *testing.T) {
+	repository := eventsource.New(&Entity{})
+	err := repository.Save(context.Background(),
+		&EntityCreated{Model: eventsource.Model{ID: "abc", Version: 1}},
+		&EntityCreated{Model: eventsource.Model{ID: "def", Version: 1}},
+	)
+	assert.NotNil(t, err)
+}
+
+func TestRepository_SaveAll(t *testing.T) {
+	ctx := context.Background()
+	repository := eventsource.New(&Entity{},
+		eventsource.WithSerializer(eventsource.NewJSONSerializer(EntityCreated{}, EntityNameSet{})),
+	)
+
+	err := repository.SaveAll(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: "abc", Version: 1}},
+		&EntityCreated{Model: eventsource.Model{ID: "def", Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: "abc", Version: 2}, Name: "blah"},
+	)
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v.(*Entity).Version)
+	assert.Equal(t, "blah", v.(*Entity).Name)
+
+	v, err = repository.Load(ctx, "def")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v.(*Entity).Version)
+
+	// stores that cannot save atomically are rejected
+	repository = eventsource.New(&Entity{},
+		eventsource.WithStore(struct{ eventsource.Store }{Store: repository.Store()}),
+	)
+	err = repository.SaveAll(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: "abc", Version: 3}},
+		&EntityCreated{Model: eventsource.Model{ID: "def", Version: 2}},
+	)
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrUnsupported))
+}
+
 func TestWithObservers(t *testing.T) {
 	captured := []eventsource.Event{}
 	observer := func(event eventsource.Event) {
@@ -292,3 +335,97 @@ func TestApplyNopCommand(t *testing.T) {
 		assert.Equal(t, 0, version)
 	})
 }
+
+// racingStore simulates a concurrent writer by saving a competing record immediately
+// before each of the first races calls to SaveVersion
+type racingStore struct {
+	eventsource.VersionedStore
+	races int
+}
+
+func (s *racingStore) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if s.races > 0 {
+		s.races--
+		data := fmt.Sprintf(`{"t":"EntityCreated","d":{"ID":%q,"Version":%v}}`, aggregateID, expectedVersion+1)
+		competing := eventsource.Record{Version: expectedVersion + 1, Data: []byte(data)}
+		if err := s.VersionedStore.Save(ctx, aggregateID, competing); err != nil {
+			return err
+		}
+	}
+
+	return s.VersionedStore.SaveVersion(ctx, aggregateID, expectedVersion, records...)
+}
+
+func TestApplyConcurrencyConflict(t *testing.T) {
+	store := &racingStore{
+		VersionedStore: eventsource.New(&Entity{}).Store().(eventsource.VersionedStore),
+		races:          1,
+	}
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(
+			eventsource.NewJSONSerializer(
+				EntityCreated{},
+			),
+		),
+	)
+
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "123"}}
+
+	// When
+	_, err := repo.Apply(context.Background(), cmd)
+
+	// Then
+	assert.NotNil(t, err)
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+	// And - once the race is over, the command succeeds against the new version
+	version, err := repo.Apply(context.Background(), cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func TestApplyWithRetry(t *testing.T) {
+	newRepository := func(races int, policy eventsource.RetryPolicy) *eventsource.Repository {
+		store := &racingStore{
+			VersionedStore: eventsource.New(&Entity{}).Store().(eventsource.VersionedStore),
+			races:          races,
+		}
+		return eventsource.New(&Entity{},
+			eventsource.WithStore(store),
+			eventsource.WithSerializer(
+				eventsource.NewJSONSerializer(
+					EntityCreated{},
+				),
+			),
+			eventsource.WithRetry(policy),
+			eventsource.WithDebug(ioutil.Discard),
+		)
+	}
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: "123"}}
+
+	t.Run("retries until the conflict clears", func(t *testing.T) {
+		repo := newRepository(2, eventsource.ExponentialBackoff(3, time.Millisecond, 5*time.Millisecond))
+
+		version, err := repo.Apply(context.Background(), cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, version)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		repo := newRepository(3, eventsource.ExponentialBackoff(3, time.Millisecond, 5*time.Millisecond))
+
+		_, err := repo.Apply(context.Background(), cmd)
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+	})
+
+	t.Run("respects context cancellation", func(t *testing.T) {
+		repo := newRepository(1, eventsource.ExponentialBackoff(3, time.Hour, time.Hour))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := repo.Apply(ctx, cmd)
+		assert.Equal(t, context.Canceled, err)
+	})
+}
@@ -0,0 +1,47 @@
+package eventsource
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy determines whether a command that failed with a concurrency conflict should be
+// attempted again and how long to wait before doing so
+type RetryPolicy interface {
+	// Backoff returns the delay before the next attempt given the number of attempts already
+	// made.  Returns false if no further attempts should be made
+	Backoff(attempt int) (time.Duration, bool)
+}
+
+// RetryPolicyFunc provides a func alternative for declaring a RetryPolicy
+type RetryPolicyFunc func(attempt int) (time.Duration, bool)
+
+// Backoff implements the RetryPolicy interface
+func (fn RetryPolicyFunc) Backoff(attempt int) (time.Duration, bool) {
+	return fn(attempt)
+}
+
+// ExponentialBackoff returns a RetryPolicy that makes up to maxAttempts attempts in total.  The
+// delay starts at base, doubles after each attempt up to max, and is randomized by up to half to
+// keep competing callers from retrying in lock step
+func ExponentialBackoff(maxAttempts int, base, max time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int) (time.Duration, bool) {
+		if attempt >= maxAttempts {
+			return 0, false
+		}
+
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+
+		if half := int64(delay / 2); half > 0 {
+			delay = time.Duration(half + rand.Int63n(half+1))
+		}
+
+		return delay, true
+	})
+}
@@ -0,0 +1,32 @@
+package eventsource_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	base := 10 * time.Millisecond
+	max := 40 * time.Millisecond
+	policy := eventsource.ExponentialBackoff(5, base, max)
+
+	testCases := map[int]time.Duration{
+		1: base,
+		2: 2 * base,
+		3: 4 * base,
+		4: max,
+	}
+
+	for attempt, expected := range testCases {
+		delay, ok := policy.Backoff(attempt)
+		assert.True(t, ok)
+		assert.True(t, delay >= expected/2, "attempt %v: delay %v should be at least %v", attempt, delay, expected/2)
+		assert.True(t, delay <= expected, "attempt %v: delay %v should be at most %v", attempt, delay, expected)
+	}
+
+	_, ok := policy.Backoff(5)
+	assert.False(t, ok, "no more attempts after max attempts")
+}
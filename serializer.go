@@ -15,13 +15,33 @@ type Serializer interface {
 }
 
 type jsonEvent struct {
-	Type string          `json:"t"`
-	Data json.RawMessage `json:"d"`
+	Type     string          `json:"t"`
+	Revision int             `json:"r,omitempty"`
+	Data     json.RawMessage `json:"d"`
+}
+
+// EventRevisioner may optionally be implemented by events whose schema has changed over time.  The
+// revision is recorded alongside the event so that older revisions can be upcast when read back.
+// Events that do not implement EventRevisioner are at revision 0
+type EventRevisioner interface {
+	EventRevision() int
+}
+
+// Upcaster converts the raw json of an event from one revision to the next.  The returned event
+// type allows events to be renamed as part of the upcast; a renamed event becomes revision 0 of
+// its new type, so the returned data must be in that shape and is then upcast by the upcasters
+// registered for the new type
+type Upcaster func(eventType string, data json.RawMessage) (string, json.RawMessage, error)
+
+type upcasterKey struct {
+	eventType string
+	revision  int
 }
 
 // JSONSerializer provides a simple serializer implementation
 type JSONSerializer struct {
 	eventTypes map[string]reflect.Type
+	upcasters  map[upcasterKey]Upcaster
 }
 
 // Bind registers the specified events with the serializer; may be called more than once
@@ -32,6 +52,40 @@ func (j *JSONSerializer) Bind(events ...Event) {
 	}
 }
 
+// RegisterUpcaster registers an upcaster that converts events of the specified type and revision
+// into revision+1, or into revision 0 of another type if the upcaster renames the event.  Upcasters
+// are chained so an event stored at revision 0 will pass through the upcasters for revisions 0, 1,
+// 2, ... until no further upcaster is registered
+func (j *JSONSerializer) RegisterUpcaster(eventType string, revision int, upcaster Upcaster) {
+	j.upcasters[upcasterKey{eventType: eventType, revision: revision}] = upcaster
+}
+
+// upcast applies the registered upcasters to the event until it reaches its latest revision
+func (j *JSONSerializer) upcast(wrapper jsonEvent) (jsonEvent, error) {
+	for {
+		upcaster, ok := j.upcasters[upcasterKey{eventType: wrapper.Type, revision: wrapper.Revision}]
+		if !ok {
+			return wrapper, nil
+		}
+
+		eventType, data, err := upcaster(wrapper.Type, wrapper.Data)
+		if err != nil {
+			return jsonEvent{}, NewError(err, ErrInvalidEncoding, "unable to upcast event, %v, from revision %v", wrapper.Type, wrapper.Revision)
+		}
+
+		revision := wrapper.Revision + 1
+		if eventType != wrapper.Type {
+			revision = 0
+		}
+
+		wrapper = jsonEvent{
+			Type:     eventType,
+			Revision: revision,
+			Data:     data,
+		}
+	}
+}
+
 // MarshalEvent converts an event into its persistent type, Record
 func (j *JSONSerializer) MarshalEvent(v Event) (Record, error) {
 	eventType, _ := EventType(v)
@@ -41,9 +95,15 @@ func (j *JSONSerializer) MarshalEvent(v Event) (Record, error) {
 		return Record{}, err
 	}
 
+	revision := 0
+	if v, ok := v.(EventRevisioner); ok {
+		revision = v.EventRevision()
+	}
+
 	data, err = json.Marshal(jsonEvent{
-		Type: eventType,
-		Data: json.RawMessage(data),
+		Type:     eventType,
+		Revision: revision,
+		Data:     json.RawMessage(data),
 	})
 	if err != nil {
 		return Record{}, NewError(err, ErrInvalidEncoding, "unable to encode event")
@@ -63,6 +123,11 @@ func (j *JSONSerializer) UnmarshalEvent(record Record) (Event, error) {
 		return nil, NewError(err, ErrInvalidEncoding, "unable to unmarshal event")
 	}
 
+	wrapper, err = j.upcast(wrapper)
+	if err != nil {
+		return nil, err
+	}
+
 	t, ok := j.eventTypes[wrapper.Type]
 	if !ok {
 		return nil, NewError(err, ErrUnboundEventType, "unbound event type, %v", wrapper.Type)
@@ -97,6 +162,7 @@ func (j *JSONSerializer) MarshalAll(events ...Event) (History, error) {
 func NewJSONSerializer(events ...Event) *JSONSerializer {
 	serializer := &JSONSerializer{
 		eventTypes: map[string]reflect.Type{},
+		upcasters:  map[upcasterKey]Upcaster{},
 	}
 	serializer.Bind(events...)
 
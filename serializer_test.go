@@ -1,6 +1,8 @@
 package eventsource_test
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/altairsix/eventsource"
@@ -55,3 +57,112 @@ func TestJSONSerializer_MarshalAll(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, &event, found)
 }
+
+type EntityLabelled struct {
+	eventsource.Model
+	Label string
+}
+
+func (EntityLabelled) EventRevision() int {
+	return 1
+}
+
+func TestJSONSerializer_Upcaster(t *testing.T) {
+	serializer := eventsource.NewJSONSerializer(EntityLabelled{})
+
+	// EntityNamed revision 0 renamed to EntityLabelled revision 0
+	serializer.RegisterUpcaster("EntityNamed", 0, func(eventType string, data json.RawMessage) (string, json.RawMessage, error) {
+		return "EntityLabelled", data, nil
+	})
+
+	// EntityLabelled revision 0 -> 1: Name field renamed to Label
+	serializer.RegisterUpcaster("EntityLabelled", 0, func(eventType string, data json.RawMessage) (string, json.RawMessage, error) {
+		v := map[string]interface{}{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return "", nil, err
+		}
+		v["Label"] = v["Name"]
+		delete(v, "Name")
+
+		data, err := json.Marshal(v)
+		return eventType, data, err
+	})
+
+	testCases := map[string]struct {
+		Data string
+	}{
+		"renamed": {
+			Data: `{"t":"EntityNamed","d":{"ID":"abc","Version":1,"Name":"blah"}}`,
+		},
+		"revision 0": {
+			Data: `{"t":"EntityLabelled","d":{"ID":"abc","Version":1,"Name":"blah"}}`,
+		},
+		"revision 1": {
+			Data: `{"t":"EntityLabelled","r":1,"d":{"ID":"abc","Version":1,"Label":"blah"}}`,
+		},
+	}
+
+	expected := &EntityLabelled{
+		Model: eventsource.Model{ID: "abc", Version: 1},
+		Label: "blah",
+	}
+
+	for label, tc := range testCases {
+		t.Run(label, func(t *testing.T) {
+			event, err := serializer.UnmarshalEvent(eventsource.Record{Version: 1, Data: []byte(tc.Data)})
+			assert.Nil(t, err)
+			assert.Equal(t, expected, event)
+		})
+	}
+
+	// new events are recorded at their current revision
+	record, err := serializer.MarshalEvent(expected)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"t":"EntityLabelled","r":1,"d":{"ID":"abc","Version":1,"At":"0001-01-01T00:00:00Z","Label":"blah"}}`, string(record.Data))
+}
+
+func TestJSONSerializer_UpcasterRenameThenUpcast(t *testing.T) {
+	serializer := eventsource.NewJSONSerializer(EntityLabelled{})
+
+	// EntityTitled revisions 0 -> 1 -> 2, the last of which renames it to EntityLabelled
+	serializer.RegisterUpcaster("EntityTitled", 0, rename("Heading", "Title"))
+	serializer.RegisterUpcaster("EntityTitled", 1, rename("Title", "Name"))
+	serializer.RegisterUpcaster("EntityTitled", 2, func(eventType string, data json.RawMessage) (string, json.RawMessage, error) {
+		return "EntityLabelled", data, nil
+	})
+
+	// the renamed event continues from EntityLabelled revision 0 rather than revision 3
+	serializer.RegisterUpcaster("EntityLabelled", 0, rename("Name", "Label"))
+
+	event, err := serializer.UnmarshalEvent(eventsource.Record{
+		Version: 1,
+		Data:    []byte(`{"t":"EntityTitled","d":{"ID":"abc","Version":1,"Heading":"blah"}}`),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &EntityLabelled{Model: eventsource.Model{ID: "abc", Version: 1}, Label: "blah"}, event)
+}
+
+// rename returns an upcaster that renames a field of the event
+func rename(from, to string) eventsource.Upcaster {
+	return func(eventType string, data json.RawMessage) (string, json.RawMessage, error) {
+		v := map[string]interface{}{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return "", nil, err
+		}
+		v[to] = v[from]
+		delete(v, from)
+
+		data, err := json.Marshal(v)
+		return eventType, data, err
+	}
+}
+
+func TestJSONSerializer_UpcasterError(t *testing.T) {
+	serializer := eventsource.NewJSONSerializer(EntityLabelled{})
+	serializer.RegisterUpcaster("EntityLabelled", 0, func(eventType string, data json.RawMessage) (string, json.RawMessage, error) {
+		return "", nil, errors.New("boom")
+	})
+
+	_, err := serializer.UnmarshalEvent(eventsource.Record{Data: []byte(`{"t":"EntityLabelled","d":{}}`)})
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrInvalidEncoding))
+}
@@ -0,0 +1,156 @@
+package singleton
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// maxBatchGetKeys is the maximum number of keys dynamodb accepts per BatchGetItem call
+const maxBatchGetKeys = 100
+
+// Reservation describes the current holder of a resource
+type Reservation struct {
+	Resource
+	ExpiresAt time.Time
+}
+
+// Renew reserves the resource for the period specified provided the resource is unreserved,
+// already held by resource.Owner, or its previous reservation has expired.  Unlike Reserve, an
+// expired reservation held by someone else will be taken over, which makes Renew suitable for
+// leases that must move when their holder dies.  Expiry is held to the millisecond so d may be
+// shorter than a second
+func (r *Registry) Renew(ctx context.Context, resource Resource, d time.Duration) error {
+	now := time.Now()
+	expiresAt := unixMillis(now.Add(d))
+	item, err := dynamodbattribute.MarshalMap(&record{
+		Key:             resource.Key(),
+		Owner:           resource.Owner,
+		ExpiresAt:       (expiresAt + 999) / 1000,
+		ExpiresAtMillis: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// reservations made by Reserve only carry their expiry in seconds
+	_, err = r.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#key) or #owner = :owner or #expiresMs < :nowMs or (attribute_not_exists(#expiresMs) and #expires < :now)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":       aws.String(HashKey),
+			"#owner":     aws.String(OwnerField),
+			"#expires":   aws.String(ExpiresField),
+			"#expiresMs": aws.String(ExpiresMillisField),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(resource.Owner)},
+			":now":   {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+			":nowMs": {N: aws.String(strconv.FormatInt(unixMillis(now), 10))},
+		},
+	})
+	if err != nil {
+		if v, ok := err.(awserr.Error); ok && v.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return eventsource.NewError(err, ErrIsAlreadyReserved, "%v resource already reserved, %v", resource.Type, resource.ID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Relinquish releases the reservation only if it is still held by resource.Owner.  Relinquishing
+// a resource held by someone else is not an error
+func (r *Registry) Relinquish(ctx context.Context, resource Resource) error {
+	_, err := r.api.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			HashKey: {S: aws.String(resource.Key())},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String(OwnerField),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(resource.Owner)},
+		},
+	})
+	if err != nil {
+		if v, ok := err.(awserr.Error); ok && v.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Reservations returns the unexpired reservations held against the specified resources.  The Owner
+// of each resource passed in is ignored; the Owner of each Reservation returned is the current holder
+func (r *Registry) Reservations(ctx context.Context, resources ...Resource) ([]Reservation, error) {
+	byKey := map[string]Resource{}
+	for _, resource := range resources {
+		byKey[resource.Key()] = resource
+	}
+
+	var keys []map[string]*dynamodb.AttributeValue
+	for key := range byKey {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			HashKey: {S: aws.String(key)},
+		})
+	}
+
+	now := time.Now()
+	var reservations []Reservation
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGetKeys {
+			n = maxBatchGetKeys
+		}
+
+		requests := map[string]*dynamodb.KeysAndAttributes{
+			r.tableName: {
+				Keys:           keys[:n],
+				ConsistentRead: aws.Bool(true),
+			},
+		}
+		keys = keys[n:]
+
+		for len(requests) > 0 {
+			out, err := r.api.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requests,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range out.Responses[r.tableName] {
+				v := &record{}
+				if err := dynamodbattribute.UnmarshalMap(item, v); err != nil {
+					return nil, err
+				}
+				if v.expired(now) {
+					continue
+				}
+
+				resource := byKey[v.Key]
+				resource.Owner = v.Owner
+				reservations = append(reservations, Reservation{
+					Resource:  resource,
+					ExpiresAt: v.expiresAt(),
+				})
+			}
+
+			requests = out.UnprocessedKeys
+		}
+	}
+
+	return reservations, nil
+}
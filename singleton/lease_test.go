@@ -0,0 +1,87 @@
+package singleton_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource/awscloud"
+	"github.com/altairsix/eventsource/dynamodbstore"
+	"github.com/altairsix/eventsource/singleton"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Lease(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	resource := singleton.Resource{
+		Type:  "lease",
+		ID:    "partition:1",
+		Owner: "abc",
+	}
+	other := singleton.Resource{
+		Type:  resource.Type,
+		ID:    resource.ID,
+		Owner: "def",
+	}
+
+	TempTable(t, api, func(tableName string) {
+		registry, err := singleton.New(tableName,
+			singleton.WithDynamoDB(api),
+		)
+		assert.Nil(t, err)
+
+		// Renew acquires an unreserved resource and may be called repeatedly by the owner
+		assert.Nil(t, registry.Renew(ctx, resource, time.Hour))
+		assert.Nil(t, registry.Renew(ctx, resource, time.Hour))
+
+		err = registry.Renew(ctx, other, time.Hour)
+		assert.True(t, singleton.IsAlreadyReserved(err))
+
+		reservations, err := registry.Reservations(ctx, resource)
+		assert.Nil(t, err)
+		assert.Len(t, reservations, 1)
+		assert.Equal(t, "abc", reservations[0].Owner)
+
+		// Relinquish by a non-owner leaves the reservation intact
+		assert.Nil(t, registry.Relinquish(ctx, other))
+		reservations, err = registry.Reservations(ctx, resource)
+		assert.Nil(t, err)
+		assert.Len(t, reservations, 1)
+
+		// expired reservations may be taken over
+		assert.Nil(t, registry.Renew(ctx, resource, -time.Hour))
+		assert.Nil(t, registry.Renew(ctx, other, time.Hour))
+
+		reservations, err = registry.Reservations(ctx, resource)
+		assert.Nil(t, err)
+		assert.Len(t, reservations, 1)
+		assert.Equal(t, "def", reservations[0].Owner)
+
+		assert.Nil(t, registry.Relinquish(ctx, other))
+		reservations, err = registry.Reservations(ctx, resource)
+		assert.Nil(t, err)
+		assert.Len(t, reservations, 0)
+
+		// leases shorter than a second expire on time
+		assert.Nil(t, registry.Renew(ctx, resource, 300*time.Millisecond))
+		reservations, err = registry.Reservations(ctx, resource)
+		assert.Nil(t, err)
+		if assert.Len(t, reservations, 1) {
+			assert.WithinDuration(t, time.Now().Add(300*time.Millisecond), reservations[0].ExpiresAt, 250*time.Millisecond)
+		}
+		assert.True(t, singleton.IsAlreadyReserved(registry.Renew(ctx, other, time.Hour)))
+
+		time.Sleep(400 * time.Millisecond)
+		assert.Nil(t, registry.Renew(ctx, other, time.Hour))
+	})
+}
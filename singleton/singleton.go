@@ -26,6 +26,10 @@ const (
 
 	// ExpiresField is the field that holds the expires data
 	ExpiresField = "expires"
+
+	// ExpiresMillisField holds the expiry of leases taken with Renew in unix milliseconds so that
+	// leases shorter than a second behave; ExpiresField, in unix seconds, is rounded up
+	ExpiresMillisField = "expires_ms"
 )
 
 const (
@@ -80,6 +84,29 @@ type record struct {
 	Key       string `dynamodbav:"key"`
 	Owner     string `dynamodbav:"owner"`
 	ExpiresAt int64  `dynamodbav:"expires"`
+
+	// ExpiresAtMillis is only set by Renew
+	ExpiresAtMillis int64 `dynamodbav:"expires_ms,omitempty"`
+}
+
+// expired returns true if the record expired before now
+func (r record) expired(now time.Time) bool {
+	if r.ExpiresAtMillis != 0 {
+		return r.ExpiresAtMillis < unixMillis(now)
+	}
+	return r.ExpiresAt < now.Unix()
+}
+
+// expiresAt returns the time the record expires
+func (r record) expiresAt() time.Time {
+	if r.ExpiresAtMillis != 0 {
+		return time.Unix(0, r.ExpiresAtMillis*int64(time.Millisecond))
+	}
+	return time.Unix(r.ExpiresAt, 0)
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
 
 // IsAvailable indicates whether the resource is available to be reserved; nil indicate the
@@ -211,6 +238,21 @@ func (r *Registry) WrapRepository(repo Repository) Repository {
 	})
 }
 
+// Middleware returns an eventsource.CommandMiddleware that provides the same behavior as WrapRepository
+// for use with eventsource.WithMiddleware.  If any command implements singleton.Interface, the
+// middleware will attempt to reserve the specified resource before the command is applied
+func (r *Registry) Middleware() eventsource.CommandMiddleware {
+	return func(next eventsource.ApplyFunc) eventsource.ApplyFunc {
+		return func(ctx context.Context, aggregate eventsource.Aggregate, command eventsource.Command) ([]eventsource.Event, error) {
+			if err := r.reserve(ctx, command); err != nil {
+				return nil, err
+			}
+
+			return next(ctx, aggregate, command)
+		}
+	}
+}
+
 // IsAlreadyReserved returns true if the error indicates the resource already exists and is reserved by someone else
 func IsAlreadyReserved(err error) bool {
 	return eventsource.ErrHasCode(err, ErrIsAlreadyReserved)
@@ -186,6 +186,57 @@ func TestRegistry_Wrap(t *testing.T) {
 	})
 }
 
+func TestRegistry_Middleware(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.SkipNow()
+		return
+	}
+
+	api, err := awscloud.DynamoDB(dynamodbstore.DefaultRegion, endpoint)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	resource := singleton.Resource{
+		Type:  "email",
+		ID:    "id",
+		Owner: "user-1",
+	}
+
+	TempTable(t, api, func(tableName string) {
+		registry, err := singleton.New(tableName,
+			singleton.WithDynamoDB(api),
+		)
+		assert.Nil(t, err)
+
+		// user-1 allocates it
+		err = registry.Reserve(ctx, resource, time.Hour)
+		assert.Nil(t, err)
+
+		applied := 0
+		fn := registry.Middleware()(func(ctx context.Context, aggregate eventsource.Aggregate, command eventsource.Command) ([]eventsource.Event, error) {
+			applied++
+			return nil, nil
+		})
+
+		// the original allocator can apply the command
+		_, err = fn(ctx, nil, Command{
+			ID:    resource.ID,
+			Owner: resource.Owner,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, applied)
+
+		// but another user cannot
+		_, err = fn(ctx, nil, Command{
+			ID:    resource.ID,
+			Owner: resource.Owner + "blah",
+		})
+		assert.True(t, singleton.IsAlreadyReserved(err))
+		assert.Equal(t, 1, applied)
+	})
+}
+
 type Command struct {
 	eventsource.CommandModel
 	ID    string
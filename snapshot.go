@@ -0,0 +1,74 @@
+package eventsource
+
+import (
+	"context"
+	"sync"
+)
+
+// Snapshot holds the serialized state of an aggregate as of a specific version
+type Snapshot struct {
+	// Version contains the version of the aggregate at the time the snapshot was taken
+	Version int
+
+	// Data contains the aggregate in serialized form
+	Data []byte
+}
+
+// SnapshotStore provides an abstraction for the Repository to save and load snapshots
+type SnapshotStore interface {
+	// SaveSnapshot saves the snapshot for the specified aggregate.  Snapshots older than the
+	// one currently stored are ignored
+	SaveSnapshot(ctx context.Context, aggregateID string, snapshot Snapshot) error
+
+	// LoadSnapshot returns the most recent snapshot of the specified aggregate; returns an
+	// error with code ErrSnapshotNotFound if no snapshot exists
+	LoadSnapshot(ctx context.Context, aggregateID string) (Snapshot, error)
+}
+
+// Snapshotter is an optional interface that an Aggregate can implement to allow its state to
+// be captured in snapshots
+type Snapshotter interface {
+	// MarshalSnapshot serializes the current state of the aggregate
+	MarshalSnapshot() ([]byte, error)
+
+	// UnmarshalSnapshot restores the aggregate from data previously returned by MarshalSnapshot
+	UnmarshalSnapshot(data []byte) error
+}
+
+// memorySnapshotStore provides an in-memory implementation of SnapshotStore
+type memorySnapshotStore struct {
+	mux       *sync.Mutex
+	snapshots map[string]Snapshot
+}
+
+// NewMemorySnapshotStore returns an in-memory SnapshotStore suitable for testing only
+func NewMemorySnapshotStore() SnapshotStore {
+	return &memorySnapshotStore{
+		mux:       &sync.Mutex{},
+		snapshots: map[string]Snapshot{},
+	}
+}
+
+func (m *memorySnapshotStore) SaveSnapshot(ctx context.Context, aggregateID string, snapshot Snapshot) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if v, ok := m.snapshots[aggregateID]; ok && v.Version >= snapshot.Version {
+		return nil
+	}
+	m.snapshots[aggregateID] = snapshot
+
+	return nil
+}
+
+func (m *memorySnapshotStore) LoadSnapshot(ctx context.Context, aggregateID string) (Snapshot, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	snapshot, ok := m.snapshots[aggregateID]
+	if !ok {
+		return Snapshot{}, NewError(nil, ErrSnapshotNotFound, "no snapshot found with id, %v", aggregateID)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,117 @@
+package eventsource_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func (item *Entity) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(item)
+}
+
+func (item *Entity) UnmarshalSnapshot(data []byte) error {
+	return json.Unmarshal(data, item)
+}
+
+// loadRecorder records the fromVersion of each call to Load
+type loadRecorder struct {
+	eventsource.Store
+	fromVersions []int
+}
+
+func (s *loadRecorder) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	s.fromVersions = append(s.fromVersions, fromVersion)
+	return s.Store.Load(ctx, aggregateID, fromVersion, toVersion)
+}
+
+func TestMemorySnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	aggregateID := "abc"
+	store := eventsource.NewMemorySnapshotStore()
+
+	_, err := store.LoadSnapshot(ctx, aggregateID)
+	assert.True(t, eventsource.ErrHasCode(err, eventsource.ErrSnapshotNotFound))
+
+	err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 2, Data: []byte("b")})
+	assert.Nil(t, err)
+
+	// older snapshots are ignored
+	err = store.SaveSnapshot(ctx, aggregateID, eventsource.Snapshot{Version: 1, Data: []byte("a")})
+	assert.Nil(t, err)
+
+	snapshot, err := store.LoadSnapshot(ctx, aggregateID)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.Snapshot{Version: 2, Data: []byte("b")}, snapshot)
+}
+
+func TestWithSnapshots(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+	store := &loadRecorder{Store: eventsource.New(&Entity{}).Store()}
+	snapshots := eventsource.NewMemorySnapshotStore()
+	repo := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(
+			eventsource.NewJSONSerializer(
+				EntityCreated{},
+			),
+		),
+		eventsource.WithSnapshots(snapshots, 2),
+		eventsource.WithDebug(ioutil.Discard),
+	)
+
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: id}}
+	for i := 0; i < 2; i++ {
+		_, err := repo.Apply(ctx, cmd)
+		assert.Nil(t, err)
+	}
+
+	// Then - a snapshot is taken once the aggregate reaches version 2
+	snapshot, err := snapshots.LoadSnapshot(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, snapshot.Version)
+
+	// When
+	version, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, version)
+
+	// Then - only events after the snapshot are loaded
+	assert.Equal(t, 3, store.fromVersions[len(store.fromVersions)-1])
+
+	v, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v.(*Entity).Version)
+	assert.Equal(t, id, v.(*Entity).ID)
+}
+
+func TestWithSnapshotsCorrupt(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+	snapshots := eventsource.NewMemorySnapshotStore()
+	repo := eventsource.New(&Entity{},
+		eventsource.WithSerializer(
+			eventsource.NewJSONSerializer(
+				EntityCreated{},
+			),
+		),
+		eventsource.WithSnapshots(snapshots, 100),
+	)
+
+	cmd := &CreateEntity{CommandModel: eventsource.CommandModel{ID: id}}
+	_, err := repo.Apply(ctx, cmd)
+	assert.Nil(t, err)
+
+	err = snapshots.SaveSnapshot(ctx, id, eventsource.Snapshot{Version: 1, Data: []byte("junk")})
+	assert.Nil(t, err)
+
+	// When - the snapshot cannot be used, the aggregate is rebuilt from its history
+	v, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v.(*Entity).Version)
+}
@@ -0,0 +1,46 @@
+package sqlitestore
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CreateSQL provides sql to create the event source table.  AUTOINCREMENT guarantees ids are
+	// never reused so offsets read from the stream only ever increase
+	CreateSQL = `
+	CREATE TABLE IF NOT EXISTS ${TABLE} (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		aggregate_id TEXT NOT NULL,
+		data         BLOB,
+		version      INTEGER,
+		metadata     BLOB
+	);
+`
+
+	// CreateIndexSQL provides sql to create the index
+	CreateIndexSQL = `
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_${TABLE}
+	ON ${TABLE} (aggregate_id, version);
+`
+)
+
+func expand(template, tableName string) string {
+	return strings.Replace(template, `${TABLE}`, tableName, -1)
+}
+
+// CreateIfNotExists creates the specified table and index(es) in the db if they do not already exist
+func CreateIfNotExists(db DB, tableName string) error {
+	_, err := db.Exec(expand(CreateSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create table")
+	}
+
+	_, err = db.Exec(expand(CreateIndexSQL, tableName))
+	if err != nil {
+		return errors.Wrap(err, "unable to create index")
+	}
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/internal/sqltx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+const (
+	insertSQL = `INSERT INTO ${TABLE} (aggregate_id, data, version, metadata) VALUES (?, ?, ?, ?)`
+	selectSQL = `SELECT data, version, metadata FROM ${TABLE} WHERE aggregate_id = ? AND version >= ? AND version <= ? ORDER BY version ASC`
+	readSQL   = `SELECT id, aggregate_id, data, version, metadata FROM ${TABLE} WHERE id >= ? ORDER BY id LIMIT ?`
+)
+
+// DB provides a smaller surface area for the db calls used; Exec is only used by the create function
+type DB interface {
+	// Exec is implemented by *sql.DB and *sql.Tx
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	// PrepareContext is implemented by *sql.DB and *sql.Tx
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	// Query is implemented by *sql.DB and *sql.Tx
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Accessor provides a standard interface to allow the store to obtain a db connection
+type Accessor interface {
+	// Open requests a new connection
+	Open(ctx context.Context) (DB, error)
+
+	// Close will be called when the store is finished with the connection
+	Close(DB) error
+}
+
+// Store provides an eventsource.Store implementation backed by sqlite.  As sqlite permits a single
+// writer at a time, open the database with _txlock=immediate so concurrent saves wait for one
+// another rather than failing with SQLITE_BUSY
+type Store struct {
+	tableName string
+	accessor  Accessor
+}
+
+func (s *Store) expand(statement string) string {
+	return strings.Replace(statement, "${TABLE}", s.tableName, -1)
+}
+
+func (s *Store) maxVersion(ctx context.Context, db DB, aggregateID string) (int, error) {
+	row, err := db.Query(expand("SELECT MAX(version) FROM ${TABLE} WHERE aggregate_id = ?", s.tableName), aggregateID)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to query database")
+	}
+	defer row.Close()
+
+	maxVersion := 0
+	if row.Next() {
+		v := sql.NullInt64{}
+		if err := row.Scan(&v); err != nil {
+			return 0, errors.Wrap(err, "unable to read version info from database")
+		}
+		maxVersion = int(v.Int64)
+	}
+
+	return maxVersion, nil
+}
+
+// Save the provided serialized records to the store
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	items := append(eventsource.History(nil), records...)
+	sort.Sort(items)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion >= items[0].Version {
+			return s.isIdempotent(ctx, tx, aggregateID, items...)
+		}
+
+		return s.insertRecords(ctx, tx, aggregateID, items...)
+	})
+}
+
+// SaveVersion saves the provided records only if the aggregate is currently at expectedVersion; implements
+// eventsource.VersionedStore
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		maxVersion, err := s.maxVersion(ctx, tx, aggregateID)
+		if err != nil {
+			return errors.Wrap(err, "save failed; unable to connect to db")
+		}
+
+		if maxVersion != expectedVersion {
+			return s.isIdempotent(ctx, tx, aggregateID, records...)
+		}
+
+		return s.insertRecords(ctx, tx, aggregateID, records...)
+	})
+}
+
+// SaveAll saves the records of several aggregates within a single transaction; implements
+// eventsource.BatchStore
+func (s *Store) SaveAll(ctx context.Context, batches ...eventsource.Batch) error {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return sqltx.WithTx(ctx, db, func(tx sqltx.DB) error {
+		for _, batch := range batches {
+			if len(batch.Records) == 0 {
+				continue
+			}
+
+			maxVersion, err := s.maxVersion(ctx, tx, batch.AggregateID)
+			if err != nil {
+				return errors.Wrap(err, "save failed; unable to connect to db")
+			}
+
+			items := append(eventsource.History(nil), batch.Records...)
+			sort.Sort(items)
+
+			if maxVersion >= items[0].Version {
+				if err := s.isIdempotent(ctx, tx, batch.AggregateID, items...); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.insertRecords(ctx, tx, batch.AggregateID, items...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) insertRecords(ctx context.Context, db DB, aggregateID string, records ...eventsource.Record) error {
+	stmt, err := db.PrepareContext(ctx, s.expand(insertSQL))
+	if err != nil {
+		return errors.Wrap(err, "unable to prepare statement")
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		metadata, err := eventsource.MarshalMetadata(record.Metadata)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal metadata for version %v of aggregate, %v", record.Version, aggregateID)
+		}
+
+		_, err = stmt.Exec(aggregateID, record.Data, record.Version, metadata)
+		if err != nil {
+			if isVersionConflict(err) {
+				return eventsource.NewError(err, eventsource.ErrConcurrencyConflict, "unable to save records; version %v of aggregate, %v, already exists", record.Version, aggregateID)
+			}
+			return errors.Wrapf(err, "unable to insert version %v for aggregate, %v", record.Version, aggregateID)
+		}
+	}
+
+	return nil
+}
+
+// isVersionConflict returns true if err is a unique violation i.e. the version already exists
+func isVersionConflict(err error) bool {
+	v, ok := errors.Cause(err).(sqlite3.Error)
+	return ok && v.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func (s *Store) isIdempotent(ctx context.Context, db DB, aggregateID string, records ...eventsource.Record) error {
+	segments := append(eventsource.History(nil), records...)
+	sort.Sort(segments)
+
+	fromVersion := segments[0].Version
+	toVersion := segments[len(segments)-1].Version
+	loaded, err := s.doLoad(ctx, db, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve version %v-%v for aggregate, %v", fromVersion, toVersion, aggregateID)
+	}
+
+	if !segments.SameEvents(loaded) {
+		return eventsource.NewError(nil, eventsource.ErrConcurrencyConflict, "unable to save records; conflicting records detected for aggregate, %v", aggregateID)
+	}
+
+	return nil
+}
+
+// Load the history of events up to the version specified; when version is
+// 0, all events will be loaded
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	return s.doLoad(ctx, db, aggregateID, fromVersion, toVersion)
+}
+
+func (s *Store) doLoad(ctx context.Context, db DB, aggregateID string, initialVersion, version int) (eventsource.History, error) {
+	if version == 0 {
+		version = math.MaxInt32
+	}
+
+	rows, err := db.Query(s.expand(selectSQL), aggregateID, initialVersion, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to query rows")
+	}
+	defer rows.Close()
+
+	history := eventsource.History{}
+	for rows.Next() {
+		record := eventsource.Record{}
+		var metadata []byte
+		if err := rows.Scan(&record.Data, &record.Version, &metadata); err != nil {
+			return nil, errors.Wrap(err, "load failed; unable to parse row")
+		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrap(err, "load failed; unable to parse metadata")
+		}
+		history = append(history, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to read rows")
+	}
+
+	return history, nil
+}
+
+// Read implements the eventsource.StreamReader interface; offsets begin at 1
+func (s *Store) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	db, err := s.accessor.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "load failed; unable to connect to db")
+	}
+	defer s.accessor.Close(db)
+
+	records := make([]eventsource.StreamRecord, 0, recordCount)
+	rows, err := db.Query(s.expand(readSQL), int64(startingOffset), recordCount)
+	if err != nil {
+		return nil, errors.Wrap(err, "read failed; unable to read records from db")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record := eventsource.StreamRecord{}
+		var metadata []byte
+		if err := rows.Scan(&record.Offset, &record.AggregateID, &record.Data, &record.Version, &metadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to scan stream record from db")
+		}
+		if record.Metadata, err = eventsource.UnmarshalMetadata(metadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse metadata of stream record")
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "read failed; unable to read records from db")
+	}
+
+	return records, nil
+}
+
+// New returns a new sqlite backed eventsource.Store
+func New(tableName string, accessor Accessor) (*Store, error) {
+	return &Store{
+		tableName: tableName,
+		accessor:  accessor,
+	}, nil
+}
@@ -0,0 +1,417 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/sqlitestore"
+	"github.com/stretchr/testify/assert"
+)
+
+type Accessor struct {
+	db sqlitestore.DB
+}
+
+func (a Accessor) Open(ctx context.Context) (sqlitestore.DB, error) {
+	return a.db, nil
+}
+
+func (a Accessor) Close(db sqlitestore.DB) error {
+	return nil
+}
+
+func TestStore_ImplementsStore(t *testing.T) {
+	v, err := sqlitestore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.Store = v
+	assert.NotNil(t, store)
+}
+
+func TestStore_ImplementsStreamReader(t *testing.T) {
+	v, err := sqlitestore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var reader eventsource.StreamReader = v
+	assert.NotNil(t, reader)
+}
+
+func TestStore_SaveEmpty(t *testing.T) {
+	s, err := sqlitestore.New("blah", nil)
+	assert.Nil(t, err)
+
+	err = s.Save(context.Background(), "abc")
+	assert.Nil(t, err, "no records saved; guaranteed to work")
+}
+
+func TestStore_SaveAndFetch(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{
+				Version: 1,
+				Data:    []byte("a"),
+			},
+			{
+				Version: 2,
+				Data:    []byte("b"),
+			},
+			{
+				Version: 3,
+				Data:    []byte("c"),
+			},
+		}
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+		assert.Len(t, found, len(history))
+	})
+}
+
+func TestStore_SaveAndRead(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{
+				Version: 1,
+				Data:    []byte("a"),
+			},
+			{
+				Version: 2,
+				Data:    []byte("b"),
+			},
+			{
+				Version: 3,
+				Data:    []byte("c"),
+			},
+		}
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := store.Read(ctx, 0, len(history))
+		assert.Nil(t, err)
+		assert.Len(t, found, len(history))
+
+		for _, item := range found {
+			assert.NotZero(t, item.Offset)
+			assert.NotZero(t, item.AggregateID)
+			assert.NotZero(t, item.Data)
+			assert.NotZero(t, item.Version)
+		}
+	})
+}
+
+func TestStore_SaveIdempotent(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{
+				Version: 1,
+				Data:    []byte("a"),
+			},
+			{
+				Version: 2,
+				Data:    []byte("b"),
+			},
+			{
+				Version: 3,
+				Data:    []byte("c"),
+			},
+		}
+		// initial save
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		// When - save it again
+		err = store.Save(ctx, aggregateID, history...)
+		// Then - verify no errors e.g. idempotent
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+		assert.Len(t, found, len(history))
+	})
+}
+
+func TestStore_SaveOptimisticLock(t *testing.T) {
+	ctx := context.Background()
+
+	WithRollback(t, func(db DB, tableName string) {
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		initial := eventsource.History{
+			{
+				Version: 1,
+				Data:    []byte("a"),
+			},
+			{
+				Version: 2,
+				Data:    []byte("b"),
+			},
+		}
+		// initial save
+		err = store.Save(ctx, aggregateID, initial...)
+		assert.Nil(t, err)
+
+		overlap := eventsource.History{
+			{
+				Version: 2,
+				Data:    []byte("c"),
+			},
+			{
+				Version: 3,
+				Data:    []byte("d"),
+			},
+		}
+		// save overlapping events; should not be allowed
+		err = store.Save(ctx, aggregateID, overlap...)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestStore_LoadPartition(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		history := eventsource.History{
+			{
+				Version: 1,
+				Data:    []byte("a"),
+			},
+			{
+				Version: 2,
+				Data:    []byte("b"),
+			},
+			{
+				Version: 3,
+				Data:    []byte("c"),
+			},
+		}
+		ctx := context.Background()
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 1)
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, history[0:1], found)
+	})
+}
+
+func TestStore_ImplementsVersionedStore(t *testing.T) {
+	v, err := sqlitestore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.VersionedStore = v
+	assert.NotNil(t, store)
+}
+
+func TestStore_SaveVersion(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		initial := eventsource.Record{Version: 1, Data: []byte("a")}
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// saving the same record again is idempotent
+		err = store.SaveVersion(ctx, aggregateID, 0, initial)
+		assert.Nil(t, err)
+
+		// a different record at the same version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, Data: []byte("b")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		// a stale expected version is a conflict
+		err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 3, Data: []byte("c")})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Len(t, found, 2)
+	})
+}
+
+func TestStore_SaveMetadata(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		aggregateID := "abc"
+		metadata := eventsource.Metadata{eventsource.CorrelationIDKey: "123", "tenant": "acme"}
+		history := eventsource.History{
+			{Version: 1, Data: []byte("a"), Metadata: metadata},
+			{Version: 2, Data: []byte("b")},
+		}
+		err = store.Save(ctx, aggregateID, history...)
+		assert.Nil(t, err)
+
+		found, err := store.Load(ctx, aggregateID, 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, history, found)
+
+		records, err := store.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, metadata, records[0].Metadata)
+		assert.Nil(t, records[1].Metadata)
+
+		// a retry of the same save is idempotent even if its metadata differs
+		retry := eventsource.History{
+			{Version: 1, Data: []byte("a"), Metadata: eventsource.Metadata{eventsource.CorrelationIDKey: "456"}},
+			{Version: 2, Data: []byte("b")},
+		}
+		assert.Nil(t, store.Save(ctx, aggregateID, retry...))
+	})
+}
+
+func TestStore_ImplementsBatchStore(t *testing.T) {
+	v, err := sqlitestore.New("blah", nil)
+	assert.Nil(t, err)
+
+	var store eventsource.BatchStore = v
+	assert.NotNil(t, store)
+}
+
+func TestStore_SaveAll(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		batches := []eventsource.Batch{
+			{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("a")}}},
+			{AggregateID: "def", Records: eventsource.History{{Version: 1, Data: []byte("b")}, {Version: 2, Data: []byte("c")}}},
+		}
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		// saving the same batches again is idempotent
+		err = store.SaveAll(ctx, batches...)
+		assert.Nil(t, err)
+
+		for _, batch := range batches {
+			found, err := store.Load(ctx, batch.AggregateID, 0, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, batch.Records, found)
+		}
+
+		err = store.SaveAll(ctx, eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("x")}}})
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+	})
+}
+
+func TestCreateIfNotExists_Idempotent(t *testing.T) {
+	WithDB(t, func(db *sql.DB, tableName string) {
+		assert.Nil(t, sqlitestore.CreateIfNotExists(db, tableName))
+	})
+}
+
+func TestStore_ReadFromOffset(t *testing.T) {
+	WithRollback(t, func(db DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")}, eventsource.Record{Version: 2, Data: []byte("b")})
+		assert.Nil(t, err)
+		err = store.Save(ctx, "def", eventsource.Record{Version: 1, Data: []byte("c")})
+		assert.Nil(t, err)
+
+		records, err := store.Read(ctx, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 3)
+		assert.Equal(t, uint64(1), records[0].Offset)
+
+		records, err = store.Read(ctx, 2, 1)
+		assert.Nil(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, uint64(2), records[0].Offset)
+		assert.Equal(t, "abc", records[0].AggregateID)
+		assert.Equal(t, 2, records[0].Version)
+
+		records, err = store.Read(ctx, 4, 10)
+		assert.Nil(t, err)
+		assert.Len(t, records, 0)
+	})
+}
+
+func TestStore_SaveIsAtomic(t *testing.T) {
+	WithDB(t, func(db *sql.DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		err = store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+		assert.Nil(t, err)
+
+		// the duplicate version violates the unique index; neither record may be saved
+		err = store.Save(ctx, "abc",
+			eventsource.Record{Version: 2, Data: []byte("b")},
+			eventsource.Record{Version: 2, Data: []byte("c")},
+		)
+		assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+		found, err := store.Load(ctx, "abc", 0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, eventsource.History{{Version: 1, Data: []byte("a")}}, found)
+	})
+}
+
+func TestStore_ConcurrentSave(t *testing.T) {
+	WithDB(t, func(db *sql.DB, tableName string) {
+		ctx := context.Background()
+		store, err := sqlitestore.New(tableName, Accessor{db: db})
+		assert.Nil(t, err)
+
+		const writers = 8
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			data := []byte(strconv.Itoa(i))
+			go func() {
+				errs <- store.SaveVersion(ctx, "abc", 0, eventsource.Record{Version: 1, Data: data})
+			}()
+		}
+
+		// exactly one writer wins; the rest see a conflict
+		succeeded := 0
+		for i := 0; i < writers; i++ {
+			if err := <-errs; err == nil {
+				succeeded++
+			} else {
+				assert.True(t, eventsource.IsConcurrencyConflict(err), err.Error())
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+}
@@ -0,0 +1,53 @@
+package sqlitestore_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/altairsix/eventsource/sqlitestore"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type DB interface {
+	sqlitestore.DB
+}
+
+// WithDB opens a fresh database in a temporary file
+func WithDB(t *testing.T, fn func(db *sql.DB, tableName string)) {
+	tableName := "sample"
+
+	dir, err := ioutil.TempDir("", "sqlitestore")
+	if !assert.Nil(t, err, "unable to create temp dir") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "events.db")+"?_txlock=immediate")
+	if !assert.Nil(t, err, "unable to open connection") {
+		return
+	}
+	defer db.Close()
+
+	if err := sqlitestore.CreateIfNotExists(db, tableName); err != nil {
+		t.Errorf("unable to create table, %v", err)
+		return
+	}
+
+	fn(db, tableName)
+}
+
+func WithRollback(t *testing.T, fn func(db DB, tableName string)) {
+	WithDB(t, func(db *sql.DB, tableName string) {
+		tx, err := db.Begin()
+		if !assert.Nil(t, err, "unable to begin transaction") {
+			return
+		}
+		defer tx.Rollback()
+
+		fn(tx, tableName)
+	})
+}
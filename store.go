@@ -1,6 +1,7 @@
 package eventsource
 
 import (
+	"bytes"
 	"context"
 	"sort"
 	"sync"
@@ -13,6 +14,10 @@ type Record struct {
 
 	// Data contains the event in serialized form
 	Data []byte
+
+	// Metadata contains optional information about the circumstances under which the event
+	// was recorded; nil if none was provided
+	Metadata Metadata
 }
 
 // History represents
@@ -33,6 +38,21 @@ func (h History) Less(i, j int) bool {
 	return h[i].Version < h[j].Version
 }
 
+// SameEvents returns true if both histories hold the same versions with the same data.  Metadata
+// is ignored so that a retried save carrying different metadata, e.g. a new request id, is still
+// recognised as idempotent
+func (h History) SameEvents(other History) bool {
+	if len(h) != len(other) {
+		return false
+	}
+	for i := range h {
+		if h[i].Version != other[i].Version || !bytes.Equal(h[i].Data, other[i].Data) {
+			return false
+		}
+	}
+	return true
+}
+
 // Store provides an abstraction for the Repository to save data
 type Store interface {
 	// Save the provided serialized records to the store
@@ -44,10 +64,39 @@ type Store interface {
 	Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (History, error)
 }
 
-// memoryStore provides an in-memory implementation of Store
+// VersionedStore is an optional interface that a Store may implement to provide optimistic
+// concurrency control.  When the Store implements VersionedStore, the Repository will use
+// SaveVersion to ensure the aggregate was not modified between the time it was loaded and
+// the time the new events were saved.
+type VersionedStore interface {
+	Store
+
+	// SaveVersion saves the provided serialized records to the store only if the current
+	// version of the aggregate is expectedVersion.  An expectedVersion of 0 indicates a
+	// new aggregate.  If the version does not match, an error with code
+	// ErrConcurrencyConflict will be returned
+	SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...Record) error
+}
+
+// Batch contains the records to be saved for a single aggregate
+type Batch struct {
+	AggregateID string
+	Records     History
+}
+
+// BatchStore is implemented by stores that can save the records of several aggregates atomically
+type BatchStore interface {
+	Store
+
+	// SaveAll saves all the batches or none of them
+	SaveAll(ctx context.Context, batches ...Batch) error
+}
+
+// memoryStore provides an in-memory implementation of Store and StreamReader
 type memoryStore struct {
 	mux        *sync.Mutex
 	eventsByID map[string]History
+	stream     []StreamRecord // stream[i] has offset i+1
 }
 
 func newMemoryStore() *memoryStore {
@@ -58,6 +107,83 @@ func newMemoryStore() *memoryStore {
 }
 
 func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.save(aggregateID, records...)
+}
+
+func (m *memoryStore) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	version := m.version(aggregateID)
+	if version != expectedVersion {
+		return NewError(nil, ErrConcurrencyConflict, "expected aggregate, %v, to be at version %v; found version %v", aggregateID, expectedVersion, version)
+	}
+	if err := followsOn(aggregateID, version, records); err != nil {
+		return err
+	}
+
+	return m.save(aggregateID, records...)
+}
+
+// SaveAll validates every batch before saving any of them so that either all are saved or none.
+// The records of each aggregate, taken across batches in order, must follow on from its current
+// version
+func (m *memoryStore) SaveAll(ctx context.Context, batches ...Batch) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	versions := map[string]int{}
+	for _, batch := range batches {
+		version, ok := versions[batch.AggregateID]
+		if !ok {
+			version = m.version(batch.AggregateID)
+		}
+		if err := followsOn(batch.AggregateID, version, batch.Records); err != nil {
+			return err
+		}
+		if len(batch.Records) > 0 {
+			versions[batch.AggregateID] = version + len(batch.Records)
+		}
+	}
+
+	for _, batch := range batches {
+		if err := m.save(batch.AggregateID, batch.Records...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// version returns the current version of the aggregate; 0 if it has no records
+func (m *memoryStore) version(aggregateID string) int {
+	history := m.eventsByID[aggregateID]
+	if len(history) == 0 {
+		return 0
+	}
+	return history[len(history)-1].Version
+}
+
+// followsOn returns an error with code ErrConcurrencyConflict unless the versions of the records
+// run consecutively from version+1
+func followsOn(aggregateID string, version int, records []Record) error {
+	sorted := make(History, len(records))
+	copy(sorted, records)
+	sort.Sort(sorted)
+
+	for i, record := range sorted {
+		if expected := version + i + 1; record.Version != expected {
+			return NewError(nil, ErrConcurrencyConflict, "expected version %v of aggregate, %v; found version %v", expected, aggregateID, record.Version)
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStore) save(aggregateID string, records ...Record) error {
 	if _, ok := m.eventsByID[aggregateID]; !ok {
 		m.eventsByID[aggregateID] = History{}
 	}
@@ -66,10 +192,46 @@ func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...R
 	sort.Sort(history)
 	m.eventsByID[aggregateID] = history
 
+	for _, record := range records {
+		m.stream = append(m.stream, StreamRecord{
+			Record:      record,
+			Offset:      uint64(len(m.stream) + 1),
+			AggregateID: aggregateID,
+		})
+	}
+
 	return nil
 }
 
+// Read implements the StreamReader interface.  As with pgstore, offsets begin at 1 and increase
+// across all aggregates in the order the records were saved
+func (m *memoryStore) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]StreamRecord, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	index := uint64(0)
+	if startingOffset > 0 {
+		index = startingOffset - 1
+	}
+	if recordCount <= 0 || index >= uint64(len(m.stream)) {
+		return []StreamRecord{}, nil
+	}
+
+	available := m.stream[index:]
+	if recordCount < len(available) {
+		available = available[:recordCount]
+	}
+
+	records := make([]StreamRecord, len(available))
+	copy(records, available)
+
+	return records, nil
+}
+
 func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (History, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
 	all, ok := m.eventsByID[aggregateID]
 	if !ok {
 		return nil, NewError(nil, ErrAggregateNotFound, "no aggregate found with id, %v", aggregateID)
@@ -84,5 +246,5 @@ func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion,
 		}
 	}
 
-	return all, nil
+	return history, nil
 }
@@ -1,6 +1,7 @@
 package eventsource_test
 
 import (
+	"context"
 	"testing"
 
 	"sort"
@@ -21,3 +22,132 @@ func TestHistory_Swap(t *testing.T) {
 	assert.Equal(t, 2, history[1].Version)
 	assert.Equal(t, 3, history[2].Version)
 }
+
+func TestHistory_SameEvents(t *testing.T) {
+	history := eventsource.History{
+		{Version: 1, Data: []byte("a"), Metadata: eventsource.Metadata{eventsource.ActorKey: "joe"}},
+		{Version: 2, Data: []byte("b")},
+	}
+
+	assert.True(t, history.SameEvents(eventsource.History{
+		{Version: 1, Data: []byte("a")},
+		{Version: 2, Data: []byte("b"), Metadata: eventsource.Metadata{eventsource.ActorKey: "jane"}},
+	}))
+	assert.False(t, history.SameEvents(eventsource.History{
+		{Version: 1, Data: []byte("a")},
+		{Version: 2, Data: []byte("c")},
+	}))
+	assert.False(t, history.SameEvents(eventsource.History{
+		{Version: 1, Data: []byte("a")},
+		{Version: 3, Data: []byte("b")},
+	}))
+	assert.False(t, history.SameEvents(history[:1]))
+}
+
+func TestMemoryStore_SaveVersion(t *testing.T) {
+	ctx := context.Background()
+	aggregateID := "abc"
+	store, ok := eventsource.New(&Entity{}).Store().(eventsource.VersionedStore)
+	assert.True(t, ok, "memory store should implement VersionedStore")
+
+	err := store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, Data: []byte("a")})
+	assert.Nil(t, err)
+
+	// When - save a record expecting the original version
+	err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, Data: []byte("b")})
+
+	// Then - the save is rejected as a conflict
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+	// When - save a record expecting the current version
+	err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, Data: []byte("c")})
+
+	// Then
+	assert.Nil(t, err)
+
+	// records must follow on from the current version without gaps or duplicates
+	err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 4, Data: []byte("d")})
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+	err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 3, Data: []byte("d")}, eventsource.Record{Version: 3, Data: []byte("e")})
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+	err = store.SaveVersion(ctx, aggregateID, 2, eventsource.Record{Version: 2, Data: []byte("d")})
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+	history, err := store.Load(ctx, aggregateID, 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+
+	records, err := store.(eventsource.StreamReader).Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestMemoryStore_SaveAll(t *testing.T) {
+	ctx := context.Background()
+	store, ok := eventsource.New(&Entity{}).Store().(eventsource.BatchStore)
+	assert.True(t, ok, "memory store should implement BatchStore")
+
+	// an aggregate may appear in more than one batch
+	err := store.SaveAll(ctx,
+		eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 1, Data: []byte("a")}}},
+		eventsource.Batch{AggregateID: "def", Records: eventsource.History{{Version: 1, Data: []byte("b")}}},
+		eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 2, Data: []byte("c")}}},
+	)
+	assert.Nil(t, err)
+
+	// When - a later batch conflicts
+	err = store.SaveAll(ctx,
+		eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 3, Data: []byte("d")}}},
+		eventsource.Batch{AggregateID: "def", Records: eventsource.History{{Version: 2, Data: []byte("e")}}},
+		eventsource.Batch{AggregateID: "abc", Records: eventsource.History{{Version: 3, Data: []byte("f")}}},
+	)
+
+	// Then - none of the batches are saved
+	assert.True(t, eventsource.IsConcurrencyConflict(err))
+
+	history, err := store.Load(ctx, "abc", 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+
+	history, err = store.Load(ctx, "def", 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+
+	records, err := store.(eventsource.StreamReader).Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestMemoryStore_Read(t *testing.T) {
+	ctx := context.Background()
+	store := eventsource.New(&Entity{}).Store()
+
+	reader, ok := store.(eventsource.StreamReader)
+	assert.True(t, ok)
+
+	records, err := reader.Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 0)
+
+	assert.Nil(t, store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")}))
+	assert.Nil(t, store.Save(ctx, "def", eventsource.Record{Version: 1, Data: []byte("b")}))
+	assert.Nil(t, store.Save(ctx, "abc", eventsource.Record{Version: 2, Data: []byte("c")}))
+
+	records, err = reader.Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []eventsource.StreamRecord{
+		{Offset: 1, AggregateID: "abc", Record: eventsource.Record{Version: 1, Data: []byte("a")}},
+		{Offset: 2, AggregateID: "def", Record: eventsource.Record{Version: 1, Data: []byte("b")}},
+		{Offset: 3, AggregateID: "abc", Record: eventsource.Record{Version: 2, Data: []byte("c")}},
+	}, records)
+
+	// reads are inclusive of the starting offset
+	records, err = reader.Read(ctx, 2, 1)
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, uint64(2), records[0].Offset)
+
+	records, err = reader.Read(ctx, 4, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 0)
+}
@@ -0,0 +1,44 @@
+package subscription
+
+import (
+	"context"
+	"sync"
+)
+
+// CheckpointStore persists the progress of a subscription.  The offset stored is the offset of
+// the next record to be read, i.e. one greater than the offset of the last record processed
+type CheckpointStore interface {
+	// LoadCheckpoint returns the offset saved for the named subscription; returns 0 if the
+	// subscription has no checkpoint
+	LoadCheckpoint(ctx context.Context, name string) (uint64, error)
+
+	// SaveCheckpoint saves the offset for the named subscription
+	SaveCheckpoint(ctx context.Context, name string, offset uint64) error
+}
+
+type memoryCheckpointStore struct {
+	mux     sync.Mutex
+	offsets map[string]uint64
+}
+
+func (m *memoryCheckpointStore) LoadCheckpoint(ctx context.Context, name string) (uint64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.offsets[name], nil
+}
+
+func (m *memoryCheckpointStore) SaveCheckpoint(ctx context.Context, name string, offset uint64) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.offsets[name] = offset
+	return nil
+}
+
+// NewMemoryCheckpointStore returns an in-memory CheckpointStore suitable for testing
+func NewMemoryCheckpointStore() CheckpointStore {
+	return &memoryCheckpointStore{
+		offsets: map[string]uint64{},
+	}
+}
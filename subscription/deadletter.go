@@ -0,0 +1,216 @@
+package subscription
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrDeadLetterNotFound is returned when the requested dead letter does not exist
+	ErrDeadLetterNotFound = "DeadLetterNotFound"
+)
+
+// DeadLetter holds a record the subscription was unable to handle along with the reason why
+type DeadLetter struct {
+	// Subscription is the name of the subscription that failed to handle the record
+	Subscription string
+
+	// Record is the record that failed; its Offset, AggregateID and Version identify it
+	Record eventsource.StreamRecord
+
+	// Error is the error returned by the final attempt to handle the record
+	Error string
+
+	// Attempts is the number of times handling the record was attempted
+	Attempts int
+
+	// FailedAt is the time of the final attempt
+	FailedAt time.Time
+
+	// Redrive indicates the record should be retried the next time the subscription is idle
+	Redrive bool
+}
+
+// DeadLetterStore holds records that could not be handled.  Dead letters are keyed by the name of
+// the subscription and the offset of the record
+type DeadLetterStore interface {
+	// PutDeadLetter saves the dead letter, replacing any with the same subscription and offset
+	PutDeadLetter(ctx context.Context, letter DeadLetter) error
+
+	// GetDeadLetter returns the dead letter for the record at offset; returns an error with code
+	// ErrDeadLetterNotFound if none exists
+	GetDeadLetter(ctx context.Context, name string, offset uint64) (DeadLetter, error)
+
+	// ListDeadLetters returns the dead letters of the named subscription ordered by offset
+	ListDeadLetters(ctx context.Context, name string) ([]DeadLetter, error)
+
+	// ListRedrives returns only the dead letters of the named subscription that are marked for
+	// redrive, ordered by offset.  Subscriptions call ListRedrives each time they are idle so its
+	// cost should not grow with the number of dead letters that are not marked
+	ListRedrives(ctx context.Context, name string) ([]DeadLetter, error)
+
+	// DeleteDeadLetter removes the dead letter for the record at offset
+	DeleteDeadLetter(ctx context.Context, name string, offset uint64) error
+}
+
+// IsDeadLetterNotFound returns true if the error indicates the dead letter does not exist
+func IsDeadLetterNotFound(err error) bool {
+	return eventsource.ErrHasCode(err, ErrDeadLetterNotFound)
+}
+
+// MarkRedrive flags the dead letters at the specified offsets, or all dead letters of the
+// subscription if no offsets are given, to be retried by the subscription the next time it is idle
+func MarkRedrive(ctx context.Context, store DeadLetterStore, name string, offsets ...uint64) (int, error) {
+	letters, err := selectDeadLetters(ctx, store, name, offsets...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, letter := range letters {
+		letter.Redrive = true
+		if err := store.PutDeadLetter(ctx, letter); err != nil {
+			return i, errors.Wrapf(err, "unable to mark dead letter at offset %v for redrive", letter.Record.Offset)
+		}
+	}
+
+	return len(letters), nil
+}
+
+// Redrive delivers the dead letters at the specified offsets, or all dead letters of the
+// subscription if no offsets are given, to the handler.  Each record handled successfully is
+// removed from the store; a record that fails again has its error and attempts updated and
+// Redrive returns the error.  Returns the number of records handled successfully.
+//
+// Redriven records are handled after records that followed them in the stream so per-aggregate
+// ordering is not preserved
+func Redrive(ctx context.Context, store DeadLetterStore, name string, handler Handler, offsets ...uint64) (int, error) {
+	letters, err := selectDeadLetters(ctx, store, name, offsets...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, letter := range letters {
+		if err := redrive(ctx, store, handler, letter); err != nil {
+			return i, err
+		}
+	}
+
+	return len(letters), nil
+}
+
+func redrive(ctx context.Context, store DeadLetterStore, handler Handler, letter DeadLetter) error {
+	offset := letter.Record.Offset
+	if err := handler.Handle(ctx, letter.Record); err != nil {
+		letter.Error = err.Error()
+		letter.Attempts++
+		letter.FailedAt = time.Now()
+		letter.Redrive = false
+		if err := store.PutDeadLetter(ctx, letter); err != nil {
+			return errors.Wrapf(err, "unable to update dead letter at offset %v", offset)
+		}
+		return errors.Wrapf(err, "redrive of subscription, %v, failed on record at offset %v", letter.Subscription, offset)
+	}
+
+	if err := store.DeleteDeadLetter(ctx, letter.Subscription, offset); err != nil {
+		return errors.Wrapf(err, "unable to delete dead letter at offset %v", offset)
+	}
+
+	return nil
+}
+
+func selectDeadLetters(ctx context.Context, store DeadLetterStore, name string, offsets ...uint64) ([]DeadLetter, error) {
+	if len(offsets) == 0 {
+		return store.ListDeadLetters(ctx, name)
+	}
+
+	letters := make([]DeadLetter, 0, len(offsets))
+	for _, offset := range offsets {
+		letter, err := store.GetDeadLetter(ctx, name, offset)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+type deadLetterKey struct {
+	name   string
+	offset uint64
+}
+
+type memoryDeadLetterStore struct {
+	mux     sync.Mutex
+	letters map[deadLetterKey]DeadLetter
+}
+
+func (m *memoryDeadLetterStore) PutDeadLetter(ctx context.Context, letter DeadLetter) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.letters[deadLetterKey{name: letter.Subscription, offset: letter.Record.Offset}] = letter
+	return nil
+}
+
+func (m *memoryDeadLetterStore) GetDeadLetter(ctx context.Context, name string, offset uint64) (DeadLetter, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	letter, ok := m.letters[deadLetterKey{name: name, offset: offset}]
+	if !ok {
+		return DeadLetter{}, eventsource.NewError(nil, ErrDeadLetterNotFound, "no dead letter at offset %v for subscription, %v", offset, name)
+	}
+	return letter, nil
+}
+
+func (m *memoryDeadLetterStore) ListDeadLetters(ctx context.Context, name string) ([]DeadLetter, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	letters := []DeadLetter{}
+	for key, letter := range m.letters {
+		if key.name == name {
+			letters = append(letters, letter)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].Record.Offset < letters[j].Record.Offset
+	})
+	return letters, nil
+}
+
+func (m *memoryDeadLetterStore) ListRedrives(ctx context.Context, name string) ([]DeadLetter, error) {
+	letters, err := m.ListDeadLetters(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	marked := letters[:0]
+	for _, letter := range letters {
+		if letter.Redrive {
+			marked = append(marked, letter)
+		}
+	}
+	return marked, nil
+}
+
+func (m *memoryDeadLetterStore) DeleteDeadLetter(ctx context.Context, name string, offset uint64) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.letters, deadLetterKey{name: name, offset: offset})
+	return nil
+}
+
+// NewMemoryDeadLetterStore returns an in-memory DeadLetterStore suitable for testing
+func NewMemoryDeadLetterStore() DeadLetterStore {
+	return &memoryDeadLetterStore{
+		letters: map[deadLetterKey]DeadLetter{},
+	}
+}
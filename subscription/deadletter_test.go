@@ -0,0 +1,189 @@
+package subscription_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionRetry(t *testing.T) {
+	reader := &memoryReader{}
+	reader.Append(3)
+
+	attempts := 0
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if record.Offset == 2 {
+			attempts++
+			if attempts < 3 {
+				return errors.New("boom")
+			}
+		}
+		return nil
+	})
+
+	checkpoints := subscription.NewMemoryCheckpointStore()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	sub := subscription.New("sample", reader, handler,
+		subscription.WithCheckpointStore(checkpoints),
+		subscription.WithRetry(2, time.Millisecond),
+	)
+	assert.Equal(t, context.DeadlineExceeded, sub.Run(ctx))
+	assert.Equal(t, 3, attempts)
+
+	offset, err := checkpoints.LoadCheckpoint(context.Background(), "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), offset)
+}
+
+func TestSubscriptionDeadLetter(t *testing.T) {
+	reader := &memoryReader{}
+	reader.Append(4)
+
+	var (
+		mux     sync.Mutex
+		failing = true
+		offsets []uint64
+	)
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if record.Offset == 2 && failing {
+			return errors.New("boom")
+		}
+		offsets = append(offsets, record.Offset)
+		return nil
+	})
+	handled := func() []uint64 {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]uint64{}, offsets...)
+	}
+
+	ctx := context.Background()
+	deadLetters := subscription.NewMemoryDeadLetterStore()
+	sub := subscription.New("sample", reader, handler,
+		subscription.WithRetry(1, time.Millisecond),
+		subscription.WithDeadLetterStore(deadLetters),
+		subscription.WithPollInterval(time.Millisecond),
+	)
+
+	done := make(chan error)
+	go func() { done <- sub.Run(ctx) }()
+
+	// the failing record is dead lettered and the subscription moves on
+	for i := 0; i < 100 && len(handled()) < 3; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, []uint64{1, 3, 4}, handled())
+
+	letter, err := deadLetters.GetDeadLetter(ctx, "sample", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "sample", letter.Subscription)
+	assert.Equal(t, "abc", letter.Record.AggregateID)
+	assert.Equal(t, 2, letter.Record.Version)
+	assert.Equal(t, "boom", letter.Error)
+	assert.Equal(t, 2, letter.Attempts)
+	assert.False(t, letter.Redrive)
+
+	// once fixed, records marked for redrive are retried while the subscription is idle
+	mux.Lock()
+	failing = false
+	mux.Unlock()
+
+	n, err := subscription.MarkRedrive(ctx, deadLetters, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	for i := 0; i < 100 && len(handled()) < 4; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, []uint64{1, 3, 4, 2}, handled())
+
+	sub.Stop()
+	assert.Nil(t, <-done)
+
+	letters, err := deadLetters.ListDeadLetters(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 0)
+}
+
+func TestRedrive(t *testing.T) {
+	ctx := context.Background()
+	deadLetters := subscription.NewMemoryDeadLetterStore()
+	for _, offset := range []uint64{5, 7} {
+		err := deadLetters.PutDeadLetter(ctx, subscription.DeadLetter{
+			Subscription: "sample",
+			Record:       eventsource.StreamRecord{Offset: offset},
+			Attempts:     1,
+		})
+		assert.Nil(t, err)
+	}
+
+	_, err := subscription.Redrive(ctx, deadLetters, "sample", nil, 6)
+	assert.True(t, subscription.IsDeadLetterNotFound(err))
+
+	// failures remain in the store with their attempts updated
+	boom := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		return errors.New("still broken")
+	})
+	n, err := subscription.Redrive(ctx, deadLetters, "sample", boom, 7)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+
+	letter, err := deadLetters.GetDeadLetter(ctx, "sample", 7)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, letter.Attempts)
+	assert.Equal(t, "still broken", letter.Error)
+
+	ok := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		return nil
+	})
+	n, err = subscription.Redrive(ctx, deadLetters, "sample", ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	letters, err := deadLetters.ListDeadLetters(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 0)
+}
+
+func TestListRedrives(t *testing.T) {
+	ctx := context.Background()
+	deadLetters := subscription.NewMemoryDeadLetterStore()
+	for _, offset := range []uint64{5, 7, 9} {
+		err := deadLetters.PutDeadLetter(ctx, subscription.DeadLetter{
+			Subscription: "sample",
+			Record:       eventsource.StreamRecord{Offset: offset},
+		})
+		assert.Nil(t, err)
+	}
+
+	letters, err := deadLetters.ListRedrives(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 0)
+
+	n, err := subscription.MarkRedrive(ctx, deadLetters, "sample", 9, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	letters, err = deadLetters.ListRedrives(ctx, "sample")
+	assert.Nil(t, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, uint64(5), letters[0].Record.Offset)
+		assert.Equal(t, uint64(9), letters[1].Record.Offset)
+	}
+
+	// marking only flags the letters; all of them remain in the store
+	letters, err = deadLetters.ListDeadLetters(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Len(t, letters, 3)
+}
@@ -0,0 +1,385 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBatchSize is the number of records read at a time by default
+	DefaultBatchSize = 100
+
+	// DefaultPollInterval is how long the subscription waits by default before polling again
+	// once it has caught up
+	DefaultPollInterval = time.Second
+)
+
+// Handler processes the records delivered by a Subscription
+type Handler interface {
+	Handle(ctx context.Context, record eventsource.StreamRecord) error
+}
+
+// HandlerFunc provides a func alternative to Handler
+type HandlerFunc func(ctx context.Context, record eventsource.StreamRecord) error
+
+// Handle implements the Handler interface
+func (fn HandlerFunc) Handle(ctx context.Context, record eventsource.StreamRecord) error {
+	return fn(ctx, record)
+}
+
+// Option provides functional configuration for a *Subscription
+type Option func(*Subscription)
+
+// WithCheckpointStore specifies where the subscription saves its progress; by default progress is
+// kept in memory and lost on restart
+func WithCheckpointStore(checkpoints CheckpointStore) Option {
+	return func(s *Subscription) {
+		s.checkpoints = checkpoints
+	}
+}
+
+// WithBatchSize specifies the maximum number of records read from the StreamReader at a time
+func WithBatchSize(n int) Option {
+	return func(s *Subscription) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+// WithPollInterval specifies how long the subscription waits before polling the StreamReader
+// again once it has caught up
+func WithPollInterval(d time.Duration) Option {
+	return func(s *Subscription) {
+		s.pollInterval = d
+	}
+}
+
+// WithIdleBackoff causes the poll interval to double each time a poll returns no records, up to
+// max.  The interval is reset as soon as records are found
+func WithIdleBackoff(max time.Duration) Option {
+	return func(s *Subscription) {
+		s.maxInterval = max
+	}
+}
+
+// WithNotify causes the subscription to poll as soon as a value is received on c rather than waiting
+// out the poll interval, e.g. pgstore.Listener.Notify.  Polling on the interval continues as a
+// fallback in case notifications are missed
+func WithNotify(c <-chan struct{}) Option {
+	return func(s *Subscription) {
+		s.notify = c
+	}
+}
+
+// WithRetry causes a record whose handler fails to be retried up to retries more times before the
+// failure policy applies.  The delay between attempts starts at backoff and doubles with each retry
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(s *Subscription) {
+		if retries >= 0 {
+			s.retries = retries
+		}
+		s.retryBackoff = backoff
+	}
+}
+
+// WithDeadLetterStore causes records that still fail after all retries to be saved to the
+// DeadLetterStore so the subscription can move on.  Without a DeadLetterStore, Run returns the
+// handler error.  Dead letters marked for redrive are retried whenever the subscription is idle
+func WithDeadLetterStore(deadLetters DeadLetterStore) Option {
+	return func(s *Subscription) {
+		s.deadLetters = deadLetters
+	}
+}
+
+// WithDebug will generate additional logging useful for debugging
+func WithDebug(w io.Writer) Option {
+	return func(s *Subscription) {
+		s.writer = w
+		s.debug = true
+	}
+}
+
+// Subscription polls a StreamReader and delivers each record, in order, to a Handler.  Progress
+// is saved to a CheckpointStore after each batch so a restarted subscription resumes where it
+// left off.  Records may be delivered more than once if the subscription stops between handling a
+// record and saving the checkpoint
+type Subscription struct {
+	name         string
+	reader       eventsource.StreamReader
+	handler      Handler
+	checkpoints  CheckpointStore
+	batchSize    int
+	pollInterval time.Duration
+	maxInterval  time.Duration
+	notify       <-chan struct{}
+	retries      int
+	retryBackoff time.Duration
+	deadLetters  DeadLetterStore
+	writer       io.Writer
+	debug        bool
+
+	mux     sync.Mutex
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func (s *Subscription) logf(format string, args ...interface{}) {
+	if !s.debug {
+		return
+	}
+
+	now := time.Now().Format(time.StampMilli)
+	io.WriteString(s.writer, now)
+	io.WriteString(s.writer, " ")
+
+	fmt.Fprintf(s.writer, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		io.WriteString(s.writer, "\n")
+	}
+}
+
+// Run delivers records to the handler until the context is cancelled, Stop is called, or the
+// handler returns an error.  Run returns nil when stopped via Stop.  Stop is permanent; once
+// called, every later Run returns nil without delivering records.  A Run that returned because
+// its context was cancelled or the handler failed may be called again.  An error is returned if
+// the subscription is already running
+func (s *Subscription) Run(ctx context.Context) error {
+	s.mux.Lock()
+	if s.running {
+		s.mux.Unlock()
+		return errors.Errorf("subscription, %v, is already running", s.name)
+	}
+	s.running = true
+	done := make(chan struct{})
+	s.done = done
+	s.mux.Unlock()
+
+	defer func() {
+		s.mux.Lock()
+		s.running = false
+		s.mux.Unlock()
+		close(done)
+	}()
+
+	offset, err := s.checkpoints.LoadCheckpoint(ctx, s.name)
+	if err != nil {
+		return errors.Wrapf(err, "unable to load checkpoint for subscription, %v", s.name)
+	}
+
+	interval := s.pollInterval
+	for {
+		next, n, err := s.poll(ctx, offset)
+		if next != offset {
+			if err := s.checkpoints.SaveCheckpoint(ctx, s.name, next); err != nil {
+				return errors.Wrapf(err, "unable to save checkpoint for subscription, %v", s.name)
+			}
+			offset = next
+		}
+		if err != nil {
+			return err
+		}
+
+		if s.stopped() {
+			return nil
+		}
+
+		if n > 0 {
+			interval = s.pollInterval
+		}
+
+		// keep reading while the reader has more to give
+		if n == s.batchSize {
+			continue
+		}
+
+		if err := s.redriveMarked(ctx); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.stop:
+			timer.Stop()
+			return nil
+		case <-s.notify:
+			timer.Stop()
+			interval = s.pollInterval
+			continue
+		case <-timer.C:
+		}
+
+		if n == 0 && s.maxInterval > 0 {
+			if interval *= 2; interval > s.maxInterval {
+				interval = s.maxInterval
+			}
+		}
+	}
+}
+
+// poll reads and handles a single batch of records; returns the offset of the next record to be
+// read and the number of records read
+func (s *Subscription) poll(ctx context.Context, offset uint64) (uint64, int, error) {
+	records, err := s.reader.Read(ctx, offset, s.batchSize)
+	if err != nil {
+		return offset, 0, errors.Wrapf(err, "unable to read records from offset %v", offset)
+	}
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return offset, len(records), err
+		}
+		if s.stopped() {
+			return offset, len(records), nil
+		}
+
+		handled, err := s.handle(ctx, record)
+		if err != nil {
+			return offset, len(records), err
+		}
+		if !handled {
+			return offset, len(records), nil
+		}
+		offset = record.Offset + 1
+	}
+
+	if len(records) > 0 {
+		s.logf("Subscription, %v, handled %v record(s); next offset is %v", s.name, len(records), offset)
+	}
+
+	return offset, len(records), nil
+}
+
+// handle delivers the record to the handler applying the retry and dead letter policies.  Returns
+// false if the subscription was stopped before the record could be handled
+func (s *Subscription) handle(ctx context.Context, record eventsource.StreamRecord) (bool, error) {
+	delay := s.retryBackoff
+	attempt := 1
+	for {
+		err := s.handler.Handle(ctx, record)
+		if err == nil {
+			return true, nil
+		}
+		s.logf("Handler failed on offset %v, attempt %v: %v", record.Offset, attempt, err)
+
+		if attempt > s.retries {
+			return s.deadLetter(ctx, record, attempt, err)
+		}
+		attempt++
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-s.stop:
+			timer.Stop()
+			return false, nil
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// deadLetter saves the failed record to the DeadLetterStore, if configured, so the subscription
+// can move on; otherwise the handler error is returned
+func (s *Subscription) deadLetter(ctx context.Context, record eventsource.StreamRecord, attempts int, cause error) (bool, error) {
+	if s.deadLetters == nil {
+		return false, errors.Wrapf(cause, "subscription, %v, failed to handle record at offset %v", s.name, record.Offset)
+	}
+
+	err := s.deadLetters.PutDeadLetter(ctx, DeadLetter{
+		Subscription: s.name,
+		Record:       record,
+		Error:        cause.Error(),
+		Attempts:     attempts,
+		FailedAt:     time.Now(),
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to dead letter record at offset %v for subscription, %v", record.Offset, s.name)
+	}
+
+	s.logf("Subscription, %v, dead lettered record at offset %v", s.name, record.Offset)
+	return true, nil
+}
+
+// redriveMarked retries the dead letters that have been marked for redrive.  Records that fail
+// again remain in the DeadLetterStore with Redrive cleared
+func (s *Subscription) redriveMarked(ctx context.Context) error {
+	if s.deadLetters == nil {
+		return nil
+	}
+
+	letters, err := s.deadLetters.ListRedrives(ctx, s.name)
+	if err != nil {
+		return errors.Wrapf(err, "unable to list dead letters marked for redrive for subscription, %v", s.name)
+	}
+
+	for _, letter := range letters {
+		if s.stopped() {
+			break
+		}
+		if err := redrive(ctx, s.deadLetters, s.handler, letter); err != nil {
+			s.logf("Redrive failed: %v", err)
+			continue
+		}
+		s.logf("Subscription, %v, redrove record at offset %v", s.name, letter.Record.Offset)
+	}
+
+	return nil
+}
+
+func (s *Subscription) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop signals Run to return once the record currently being handled is complete and the
+// checkpoint has been saved.  Stop blocks until Run has returned and is safe to call more than
+// once.  A stopped Subscription cannot be run again; create a new one instead
+func (s *Subscription) Stop() {
+	s.mux.Lock()
+	if !s.stopped() {
+		close(s.stop)
+	}
+	running, done := s.running, s.done
+	s.mux.Unlock()
+
+	if running {
+		<-done
+	}
+}
+
+// New returns a new Subscription.  The name identifies the subscription within the CheckpointStore
+func New(name string, reader eventsource.StreamReader, handler Handler, opts ...Option) *Subscription {
+	s := &Subscription{
+		name:         name,
+		reader:       reader,
+		handler:      handler,
+		checkpoints:  NewMemoryCheckpointStore(),
+		batchSize:    DefaultBatchSize,
+		pollInterval: DefaultPollInterval,
+		stop:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
@@ -0,0 +1,223 @@
+package subscription_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryReader provides a StreamReader over a fixed set of records with offsets 1..n
+type memoryReader struct {
+	mux     sync.Mutex
+	records []eventsource.StreamRecord
+}
+
+func (m *memoryReader) Append(n int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for i := 0; i < n; i++ {
+		offset := uint64(len(m.records) + 1)
+		m.records = append(m.records, eventsource.StreamRecord{
+			AggregateID: "abc",
+			Offset:      offset,
+			Record:      eventsource.Record{Version: int(offset)},
+		})
+	}
+}
+
+func (m *memoryReader) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var records []eventsource.StreamRecord
+	for _, record := range m.records {
+		if record.Offset >= startingOffset && len(records) < recordCount {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func TestSubscription(t *testing.T) {
+	ctx := context.Background()
+	reader := &memoryReader{}
+	reader.Append(5)
+
+	checkpoints := subscription.NewMemoryCheckpointStore()
+
+	var (
+		mux     sync.Mutex
+		offsets []uint64
+	)
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		mux.Lock()
+		defer mux.Unlock()
+		offsets = append(offsets, record.Offset)
+		return nil
+	})
+
+	sub := subscription.New("sample", reader, handler,
+		subscription.WithCheckpointStore(checkpoints),
+		subscription.WithBatchSize(2),
+		subscription.WithPollInterval(time.Millisecond),
+		subscription.WithIdleBackoff(10*time.Millisecond),
+	)
+
+	done := make(chan error)
+	go func() { done <- sub.Run(ctx) }()
+
+	waitFor := func(n int) {
+		for i := 0; i < 100; i++ {
+			mux.Lock()
+			found := len(offsets)
+			mux.Unlock()
+			if found >= n {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %v records", n)
+	}
+
+	waitFor(5)
+	reader.Append(2)
+	waitFor(7)
+
+	sub.Stop()
+	assert.Nil(t, <-done)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7}, offsets)
+
+	offset, err := checkpoints.LoadCheckpoint(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(8), offset)
+}
+
+func TestSubscriptionHandlerError(t *testing.T) {
+	ctx := context.Background()
+	reader := &memoryReader{}
+	reader.Append(5)
+
+	checkpoints := subscription.NewMemoryCheckpointStore()
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		if record.Offset == 3 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	sub := subscription.New("sample", reader, handler, subscription.WithCheckpointStore(checkpoints))
+	err := sub.Run(ctx)
+	assert.NotNil(t, err)
+
+	// progress up to the failed record is retained
+	offset, err := checkpoints.LoadCheckpoint(ctx, "sample")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), offset)
+
+	// a new subscription resumes from the checkpoint
+	var offsets []uint64
+	handler = subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		offsets = append(offsets, record.Offset)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	sub = subscription.New("sample", reader, handler, subscription.WithCheckpointStore(checkpoints))
+	err = sub.Run(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, []uint64{3, 4, 5}, offsets)
+}
+
+func TestSubscriptionStopBeforeRun(t *testing.T) {
+	reader := &memoryReader{}
+	reader.Append(1)
+
+	called := false
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		called = true
+		return nil
+	})
+
+	sub := subscription.New("sample", reader, handler)
+	sub.Stop()
+
+	assert.Nil(t, sub.Run(context.Background()))
+	assert.False(t, called)
+
+	// Stop is permanent
+	assert.Nil(t, sub.Run(context.Background()))
+	assert.False(t, called)
+}
+
+func TestSubscriptionRunAgain(t *testing.T) {
+	reader := &memoryReader{}
+	reader.Append(1)
+
+	handled := make(chan struct{}, 1)
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		select {
+		case handled <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	sub := subscription.New("sample", reader, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sub.Run(ctx) }()
+	<-handled
+
+	assert.NotNil(t, sub.Run(context.Background()), "concurrent runs should be rejected")
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	// a subsequent run is permitted once the previous one has returned
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, sub.Run(ctx))
+}
+
+func TestSubscriptionNotify(t *testing.T) {
+	reader := &memoryReader{}
+	notify := make(chan struct{}, 1)
+
+	handled := make(chan uint64, 10)
+	handler := subscription.HandlerFunc(func(ctx context.Context, record eventsource.StreamRecord) error {
+		handled <- record.Offset
+		return nil
+	})
+
+	// the poll interval is long enough that only a notification can wake the subscription
+	sub := subscription.New("sample", reader, handler,
+		subscription.WithPollInterval(time.Hour),
+		subscription.WithNotify(notify),
+	)
+
+	done := make(chan error)
+	go func() { done <- sub.Run(context.Background()) }()
+
+	reader.Append(1)
+	notify <- struct{}{}
+
+	select {
+	case offset := <-handled:
+		assert.Equal(t, uint64(1), offset)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	sub.Stop()
+	assert.Nil(t, <-done)
+}